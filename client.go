@@ -0,0 +1,373 @@
+package keyloc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Client queries a set of providers for input sources.
+// The package-level functions use a client with the registered providers.
+type Client struct {
+	providers       []Provider
+	pollInterval    time.Duration
+	providerTimeout time.Duration
+	root            string
+	kinds           []Kind
+}
+
+// Option configures a Client created with New.
+type Option func(*Client)
+
+// WithProviders replaces the providers of the client, in the order they are
+// queried. Registered providers can be found with Lookup.
+func WithProviders(providers ...Provider) Option {
+	return func(c *Client) {
+		c.providers = append([]Provider(nil), providers...)
+	}
+}
+
+// WithProvider adds a provider after those already configured.
+func WithProvider(p Provider) Option {
+	return func(c *Client) {
+		c.providers = append(c.providers, p)
+	}
+}
+
+// WithoutProviders removes the named providers from the client.
+func WithoutProviders(names ...string) Option {
+	return func(c *Client) {
+		kept := c.providers[:0:0]
+		for _, p := range c.providers {
+			disabled := false
+			for _, name := range names {
+				if p.Name() == name {
+					disabled = true
+					break
+				}
+			}
+			if !disabled {
+				kept = append(kept, p)
+			}
+		}
+		c.providers = kept
+	}
+}
+
+// WithPollInterval sets how often Watch queries the providers for changes.
+// A zero or negative duration is ignored.
+func WithPollInterval(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.pollInterval = d
+		}
+	}
+}
+
+// WithProviderTimeout sets how long each provider may take to answer a
+// query. A provider that does not answer in time is skipped, as if it had
+// failed. A zero or negative duration disables the timeout.
+func WithProviderTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.providerTimeout = d
+	}
+}
+
+// WithRoot makes the client read configuration files relative to root, e.g.
+// "/mnt/image", instead of the running system. Providers that implement
+// RootedProvider are switched to root; the others, which query running
+// services or the display server, are left out.
+func WithRoot(root string) Option {
+	return func(c *Client) {
+		c.root = root
+	}
+}
+
+// WithKinds makes the client report only input sources of the given kinds,
+// instead of keyboard layouts and input methods. For example, on macOS
+//
+//	keyloc.New(keyloc.WithKinds(keyloc.KindKeyboard, keyloc.KindInputMethod, keyloc.KindPreferredUILanguage))
+//
+// also reports the preferred languages of the user.
+func WithKinds(kinds ...Kind) Option {
+	return func(c *Client) {
+		c.kinds = append([]Kind(nil), kinds...)
+	}
+}
+
+// defaultProviderTimeout is the default time limit of a single provider query.
+const defaultProviderTimeout = 3 * time.Second
+
+// New returns a client that queries the registered providers, as modified by opts.
+func New(opts ...Option) *Client {
+	c := &Client{
+		providers:       Providers(),
+		pollInterval:    defaultPollInterval,
+		providerTimeout: defaultProviderTimeout,
+		kinds:           defaultKinds,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.root != "" {
+		var rooted []Provider
+		for _, p := range c.providers {
+			if rp, ok := p.(RootedProvider); ok {
+				rooted = append(rooted, rp.WithRoot(c.root))
+			}
+		}
+		c.providers = rooted
+	}
+	return c
+}
+
+// Providers returns the providers of the client in the order they are queried.
+func (c *Client) Providers() []Provider {
+	return append([]Provider(nil), c.providers...)
+}
+
+// available returns the providers that can be used on this system.
+func (c *Client) available() []Provider {
+	var providers []Provider
+	for _, p := range c.providers {
+		if p.Available() {
+			providers = append(providers, p)
+		}
+	}
+	return providers
+}
+
+// Result is the outcome of querying every available provider of a client.
+type Result struct {
+	// Sources holds the input sources of the providers that succeeded.
+	Sources []InputSource
+	// Succeeded lists the names of the providers that answered, in query order.
+	Succeeded []string
+	// Failed holds the errors of the providers that did not answer, in query order.
+	Failed []*ProviderError
+}
+
+// Err returns the errors of the failed providers joined together, or nil
+// if every provider succeeded.
+func (r *Result) Err() error {
+	errs := make([]error, len(r.Failed))
+	for i, err := range r.Failed {
+		errs[i] = err
+	}
+	return errors.Join(errs...)
+}
+
+// Query queries every available provider and reports which of them
+// succeeded and which failed, alongside the input sources that were found.
+// An error is returned only if no provider is available or ctx is done.
+func (c *Client) Query(ctx context.Context) (*Result, error) {
+	providers := c.available()
+	if len(providers) == 0 {
+		return nil, ErrNoBackend
+	}
+
+	results, errs := c.queryProviders(ctx, providers)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r := &Result{Sources: c.mergeSources(results)}
+	for i, p := range providers {
+		if errs[i] != nil {
+			r.Failed = append(r.Failed, providerError(p.Name(), errs[i]))
+			continue
+		}
+		r.Succeeded = append(r.Succeeded, p.Name())
+	}
+	return r, nil
+}
+
+// InputSources returns the input sources of all available providers.
+// An input source reported by several providers is only returned once. An
+// error is returned only if every provider failed; use Query to learn
+// about individual failures.
+func (c *Client) InputSources(ctx context.Context) ([]InputSource, error) {
+	r, err := c.Query(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Succeeded) == 0 {
+		return nil, r.Err()
+	}
+	return r.Sources, nil
+}
+
+// queryProviders queries the providers concurrently and returns the input
+// sources and error of each, by index.
+func (c *Client) queryProviders(ctx context.Context, providers []Provider) ([][]InputSource, []error) {
+	results := make([][]InputSource, len(providers))
+	errs := make([]error, len(providers))
+
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = withTimeout(ctx, c.providerTimeout, p.InputSources)
+		}()
+	}
+	wg.Wait()
+
+	return results, errs
+}
+
+// withTimeout calls f with a context that expires after timeout. If f does
+// not return by then, its result is abandoned and the context error is
+// returned, so that a provider ignoring its context cannot block the caller.
+func withTimeout[T any](ctx context.Context, timeout time.Duration, f func(context.Context) (T, error)) (T, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := f(ctx)
+		done <- result{v, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// mergeSources merges the input sources of several providers, keeping only
+// those of the kinds of the client.
+func (c *Client) mergeSources(results [][]InputSource) []InputSource {
+	return mergeSources(results, c.kinds)
+}
+
+// mergeSources concatenates the input sources of several providers, in
+// order, keeping those of the given kinds and dropping sources with an ID
+// and language that were already seen. Each kept source gets its index
+// among the kept sources of its provider, the first one is the default
+// unless the provider marked another, and the first default is primary.
+func mergeSources(results [][]InputSource, kinds []Kind) []InputSource {
+	var sources []InputSource
+	seen := make(map[string]bool)
+	primary := false
+	for _, all := range results {
+		var srcs []InputSource
+		for _, src := range all {
+			if slices.Contains(kinds, src.Kind) {
+				srcs = append(srcs, src)
+			}
+		}
+		hasDefault := slices.ContainsFunc(srcs, func(src InputSource) bool { return src.Default })
+		for i, src := range srcs {
+			src.Index = i
+			src.Default = src.Default || (!hasDefault && i == 0)
+			src.Primary = false
+			key := src.ID + "\x00" + src.Language
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if src.Default && !primary {
+				src.Primary, primary = true, true
+			}
+			sources = append(sources, src)
+		}
+	}
+	return sources
+}
+
+// Languages returns the deduplicated language tags of the input sources,
+// in their order, so that the language of the primary source comes first.
+func (c *Client) Languages(ctx context.Context) ([]string, error) {
+	sources, err := c.InputSources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	langs := make([]string, 0, len(sources))
+	for _, src := range sources {
+		if src.Language == "" || seen[src.Language] {
+			continue
+		}
+		seen[src.Language] = true
+		langs = append(langs, src.Language)
+	}
+
+	return langs, nil
+}
+
+// CurrentInputSource returns the active input source as reported by the
+// first available provider that knows it.
+func (c *Client) CurrentInputSource(ctx context.Context) (InputSource, error) {
+	var firstErr error
+	for _, p := range c.available() {
+		cp, ok := p.(CurrentProvider)
+		if !ok {
+			continue
+		}
+		src, err := withTimeout(ctx, c.providerTimeout, cp.CurrentInputSource)
+		if err == nil {
+			return src, nil
+		}
+		if ctx.Err() != nil {
+			return InputSource{}, ctx.Err()
+		}
+		if firstErr == nil {
+			firstErr = providerError(p.Name(), err)
+		}
+	}
+	if firstErr == nil {
+		return InputSource{}, ErrNoBackend
+	}
+	return InputSource{}, firstErr
+}
+
+// CheckLanguage reports whether a keyboard for the primary language of lang is available.
+func (c *Client) CheckLanguage(ctx context.Context, lang string) (bool, error) {
+	return c.checkLanguage(ctx, lang, matchLanguage)
+}
+
+// CheckLanguageWithScript reports whether a keyboard for the language and script of lang is available.
+func (c *Client) CheckLanguageWithScript(ctx context.Context, lang string) (bool, error) {
+	return c.checkLanguage(ctx, lang, matchScript)
+}
+
+// CheckLanguageExact reports whether a keyboard for exactly lang is available.
+func (c *Client) CheckLanguageExact(ctx context.Context, lang string) (bool, error) {
+	return c.checkLanguage(ctx, lang, matchExact)
+}
+
+func (c *Client) checkLanguage(ctx context.Context, lang string, mode matchMode) (bool, error) {
+	sources, err := c.InputSources(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	want, err := ParseTag(lang)
+	if err != nil {
+		if mode != matchLanguage {
+			return false, err
+		}
+		want = Tag{Language: normalizeLangCode(lang)}
+	}
+
+	for _, src := range sources {
+		if src.Language != "" && matchTag(src.Language, want, mode) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
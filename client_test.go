@@ -0,0 +1,308 @@
+package keyloc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// stubProvider is a Provider with fixed results.
+type stubProvider struct {
+	name      string
+	available bool
+	sources   []InputSource
+	current   *InputSource
+	err       error
+}
+
+func (p *stubProvider) Name() string    { return p.name }
+func (p *stubProvider) Available() bool { return p.available }
+
+func (p *stubProvider) InputSources(ctx context.Context) ([]InputSource, error) {
+	return p.sources, p.err
+}
+
+// stubCurrentProvider is a stubProvider that knows the active input source.
+type stubCurrentProvider struct{ stubProvider }
+
+func (p *stubCurrentProvider) CurrentInputSource(ctx context.Context) (InputSource, error) {
+	if p.current == nil {
+		return InputSource{}, ErrNoInputSource
+	}
+	return *p.current, p.err
+}
+
+// stubRootedProvider is a stubProvider that records the root it is given.
+type stubRootedProvider struct {
+	stubProvider
+	root string
+}
+
+func (p *stubRootedProvider) WithRoot(root string) Provider {
+	rooted := *p
+	rooted.root = root
+	return &rooted
+}
+
+func TestClientInputSources(t *testing.T) {
+	us := newInputSource("us", "en-US", "English (US)", "a")
+	kr := newInputSource("kr", "ko", "Korean", "a")
+	de := newInputSource("de", "de", "German", "b")
+
+	a := &stubProvider{name: "a", available: true, sources: []InputSource{us, kr}}
+	b := &stubProvider{name: "b", available: true, sources: []InputSource{us, de}}
+	failing := &stubProvider{name: "failing", available: true, err: errors.New("boom")}
+	missing := &stubProvider{name: "missing", sources: []InputSource{newInputSource("ru", "ru", "Russian", "missing")}}
+
+	c := New(WithProviders(a, failing, missing, b))
+	got, err := c.InputSources(context.Background())
+	if err != nil {
+		t.Fatalf("InputSources() returned an error: %v", err)
+	}
+	expected := []InputSource{us, kr, de}
+	expected[0].Default, expected[0].Primary = true, true
+	expected[1].Index, expected[2].Index = 1, 1
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("InputSources() = %v, want %v", got, expected)
+	}
+
+	langs, err := c.Languages(context.Background())
+	if err != nil {
+		t.Fatalf("Languages() returned an error: %v", err)
+	}
+	if expected := []string{"en-US", "ko", "de"}; !reflect.DeepEqual(langs, expected) {
+		t.Errorf("Languages() = %v, want %v", langs, expected)
+	}
+
+	c = New(WithProviders(a, b), WithoutProviders("a"))
+	expected = []InputSource{us, de}
+	expected[0].Default, expected[0].Primary = true, true
+	expected[1].Index = 1
+	if got, _ := c.InputSources(context.Background()); !reflect.DeepEqual(got, expected) {
+		t.Errorf("InputSources() without provider a = %v, want %v", got, expected)
+	}
+
+	c = New(WithProviders(failing))
+	_, err = c.InputSources(context.Background())
+	var pe *ProviderError
+	if !errors.As(err, &pe) || pe.Provider != "failing" || !errors.Is(err, failing.err) {
+		t.Errorf("InputSources() with only failing providers error = %v, want a *ProviderError for failing", err)
+	}
+
+	c = New(WithProviders(missing))
+	if _, err := c.InputSources(context.Background()); !errors.Is(err, ErrNoBackend) {
+		t.Errorf("InputSources() without available providers error = %v, want %v", err, ErrNoBackend)
+	}
+}
+
+func TestClientQuery(t *testing.T) {
+	us := newInputSource("us", "en-US", "English (US)", "a")
+	a := &stubProvider{name: "a", available: true, sources: []InputSource{us}}
+	failing := &stubProvider{name: "failing", available: true, err: &ProviderError{Stderr: "no bus", Err: ErrCommandNotFound}}
+
+	r, err := New(WithProviders(failing, a)).Query(context.Background())
+	if err != nil {
+		t.Fatalf("Query() returned an error: %v", err)
+	}
+	us.Default, us.Primary = true, true
+	if !reflect.DeepEqual(r.Sources, []InputSource{us}) {
+		t.Errorf("Query() sources = %v, want %v", r.Sources, []InputSource{us})
+	}
+	if !reflect.DeepEqual(r.Succeeded, []string{"a"}) {
+		t.Errorf("Query() succeeded = %v, want [a]", r.Succeeded)
+	}
+	if len(r.Failed) != 1 || r.Failed[0].Provider != "failing" || r.Failed[0].Stderr != "no bus" {
+		t.Fatalf("Query() failed = %v, want the failing provider with its stderr", r.Failed)
+	}
+	if !errors.Is(r.Err(), ErrCommandNotFound) {
+		t.Errorf("Result.Err() = %v, want %v", r.Err(), ErrCommandNotFound)
+	}
+}
+
+func TestMergeSourcesOrder(t *testing.T) {
+	us := newInputSource("us", "en-US", "English (US)", "a")
+	kr := newInputSource("kr", "ko", "Korean", "a")
+	de := newInputSource("de", "de", "German", "a")
+	ru := newInputSource("ru", "ru", "Russian", "b")
+
+	// Provider a marks its second source as the default
+	a := []InputSource{us, kr, de}
+	a[1].Default = true
+	b := []InputSource{ru, us}
+
+	got := mergeSources([][]InputSource{a, b}, defaultKinds)
+	type flags struct {
+		id                 string
+		index              int
+		primary, isDefault bool
+	}
+	var gotFlags []flags
+	for _, src := range got {
+		gotFlags = append(gotFlags, flags{src.ID, src.Index, src.Primary, src.Default})
+	}
+	expected := []flags{
+		{"us", 0, false, false},
+		{"kr", 1, true, true},
+		{"de", 2, false, false},
+		{"ru", 0, false, true},
+	}
+	if !reflect.DeepEqual(gotFlags, expected) {
+		t.Errorf("mergeSources() = %v, want %v", gotFlags, expected)
+	}
+	if b[0].Default || b[0].Index != 0 {
+		t.Errorf("mergeSources() modified its input: %+v", b[0])
+	}
+
+	// A provider without sources leaves the primary source to the next one
+	got = mergeSources([][]InputSource{nil, b, a}, defaultKinds)
+	if len(got) == 0 || got[0].ID != "ru" || !got[0].Primary {
+		t.Errorf("mergeSources() without sources from the first provider = %v, want ru primary", got)
+	}
+	for _, src := range got[1:] {
+		if src.Primary {
+			t.Errorf("mergeSources() made %s primary too", src.ID)
+		}
+	}
+}
+
+func TestClientKinds(t *testing.T) {
+	ui := newInputSource("fr", "fr", "fr", "stub")
+	ui.Kind = KindPreferredUILanguage
+	us := newInputSource("us", "en-US", "English (US)", "stub")
+	kr := newInputSource("kr", "ko", "Korean", "stub")
+	kr.Kind = KindInputMethod
+	voice := newInputSource("de", "de", "de", "stub")
+	voice.Kind = KindSpeechVoice
+	p := &stubProvider{name: "stub", available: true, sources: []InputSource{ui, us, kr, voice}}
+
+	ctx := context.Background()
+	got, err := New(WithProviders(p)).InputSources(ctx)
+	if err != nil {
+		t.Fatalf("InputSources() returned an error: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "us" || got[1].ID != "kr" {
+		t.Fatalf("InputSources() = %v, want only us and kr", got)
+	}
+	// The sources are indexed among the kept ones, and the first keyboard
+	// takes the place of the preferred language as the default
+	if !got[0].Primary || !got[0].Default || got[0].Index != 0 || got[1].Default || got[1].Index != 1 {
+		t.Errorf("InputSources() = %+v, want us as the primary default with index 0 and kr with index 1", got)
+	}
+	if ok, _ := New(WithProviders(p)).CheckLanguage(ctx, "fr"); ok {
+		t.Error("CheckLanguage(fr) = true for a preferred UI language, want false")
+	}
+
+	c := New(WithProviders(p), WithKinds(KindPreferredUILanguage, KindSpeechVoice))
+	got, err = c.InputSources(ctx)
+	if err != nil {
+		t.Fatalf("InputSources() returned an error: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "fr" || got[1].ID != "de" || !got[0].Primary {
+		t.Errorf("InputSources() with WithKinds = %v, want fr and de", got)
+	}
+
+	// A default of a kind that is left out passes to the first kept source
+	kr.Default = true
+	p.sources = []InputSource{kr, us}
+	got, err = New(WithProviders(p), WithKinds(KindKeyboard)).InputSources(ctx)
+	if err != nil {
+		t.Fatalf("InputSources() returned an error: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "us" || !got[0].Default || !got[0].Primary || got[0].Index != 0 {
+		t.Errorf("InputSources() with WithKinds(KindKeyboard) = %+v, want us as the primary default", got)
+	}
+}
+
+func TestClientCheckLanguage(t *testing.T) {
+	p := &stubProvider{name: "stub", available: true, sources: []InputSource{
+		newInputSource("tw", "zh-TW", "Chinese (Taiwan)", "stub"),
+		newInputSource("rs", "sr-Latn", "Serbian (Latin)", "stub"),
+	}}
+	c := New(WithProviders(p))
+
+	tests := []struct {
+		check    func(context.Context, string) (bool, error)
+		lang     string
+		expected bool
+	}{
+		{c.CheckLanguage, "zh-CN", true},
+		{c.CheckLanguageWithScript, "zh-CN", false},
+		{c.CheckLanguageWithScript, "zh-Hant", true},
+		{c.CheckLanguageExact, "zh-Hant-TW", true},
+		{c.CheckLanguageExact, "zh-Hant-HK", false},
+		{c.CheckLanguage, "sr", true},
+		{c.CheckLanguageWithScript, "sr", false},
+		{c.CheckLanguageWithScript, "sr_RS@latin", true},
+	}
+
+	for _, test := range tests {
+		got, err := test.check(context.Background(), test.lang)
+		if err != nil {
+			t.Errorf("check(%q) returned an error: %v", test.lang, err)
+		}
+		if got != test.expected {
+			t.Errorf("check(%q) = %v, want %v", test.lang, got, test.expected)
+		}
+	}
+}
+
+func TestClientCurrentInputSource(t *testing.T) {
+	kr := newInputSource("kr", "ko", "Korean", "b")
+	a := &stubCurrentProvider{stubProvider{name: "a", available: true}}
+	b := &stubCurrentProvider{stubProvider{name: "b", available: true, current: &kr}}
+
+	got, err := New(WithProviders(a, b)).CurrentInputSource(context.Background())
+	if err != nil {
+		t.Fatalf("CurrentInputSource() returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(got, kr) {
+		t.Errorf("CurrentInputSource() = %v, want %v", got, kr)
+	}
+
+	if _, err := New(WithProviders(a)).CurrentInputSource(context.Background()); !errors.Is(err, ErrNoInputSource) {
+		t.Errorf("CurrentInputSource() error = %v, want %v", err, ErrNoInputSource)
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	p := &stubProvider{name: "duplicate"}
+	registryMu.Lock()
+	saved := registry
+	registry = append([]Provider(nil), registry...)
+	registryMu.Unlock()
+	defer func() {
+		registryMu.Lock()
+		registry = saved
+		registryMu.Unlock()
+	}()
+
+	Register(p)
+	if got, ok := Lookup("duplicate"); !ok || got != p {
+		t.Errorf("Lookup(%q) = %v, %v, want the registered provider", "duplicate", got, ok)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() of a duplicate provider did not panic")
+		}
+	}()
+	Register(p)
+}
+
+func TestClientWithRoot(t *testing.T) {
+	live := &stubProvider{name: "live", available: true}
+	files := &stubRootedProvider{stubProvider: stubProvider{name: "files", available: true}}
+
+	c := New(WithRoot("/mnt/image"), WithProviders(live, files))
+	providers := c.Providers()
+	if len(providers) != 1 {
+		t.Fatalf("Providers() with a root = %v, want only the rooted provider", providers)
+	}
+	if p, ok := providers[0].(*stubRootedProvider); !ok || p.root != "/mnt/image" {
+		t.Errorf("Providers()[0] = %+v, want files with root /mnt/image", providers[0])
+	}
+	if files.root != "" {
+		t.Errorf("WithRoot() modified the original provider")
+	}
+}
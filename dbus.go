@@ -0,0 +1,803 @@
+package keyloc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// This file implements the small subset of the D-Bus wire protocol that
+// keyloc needs: connecting to a bus over a unix socket, authenticating with
+// EXTERNAL, and calling methods with simple arguments.
+
+// errNoBus is returned when no D-Bus address can be connected to.
+var errNoBus = errors.New("keyloc: cannot connect to the D-Bus system bus")
+
+// defaultSystemBusAddress is used when DBUS_SYSTEM_BUS_ADDRESS is not set.
+const defaultSystemBusAddress = "unix:path=/var/run/dbus/system_bus_socket"
+
+// D-Bus message types.
+const (
+	dbusMethodCall   = 1
+	dbusMethodReturn = 2
+	dbusError        = 3
+	dbusSignal       = 4
+)
+
+// D-Bus header field codes.
+const (
+	dbusFieldPath        = 1
+	dbusFieldInterface   = 2
+	dbusFieldMember      = 3
+	dbusFieldErrorName   = 4
+	dbusFieldReplySerial = 5
+	dbusFieldDestination = 6
+	dbusFieldSender      = 7
+	dbusFieldSignature   = 8
+)
+
+// dbusVariant is a D-Bus variant: a value along with its signature.
+type dbusVariant struct {
+	Sig   string
+	Value any
+}
+
+// dbusObjectPath distinguishes object paths from strings when encoding.
+type dbusObjectPath string
+
+// dbusSignature distinguishes signatures from strings when encoding.
+type dbusSignature string
+
+// dbusMessage is a decoded D-Bus message.
+type dbusMessage struct {
+	Type   byte
+	Flags  byte
+	Serial uint32
+	Fields map[byte]dbusVariant
+	Body   []any
+}
+
+func (m *dbusMessage) field(code byte) string {
+	switch v := m.Fields[code].Value.(type) {
+	case string:
+		return v
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	}
+	return ""
+}
+
+// dbusErrorReply is an error reply to a D-Bus method call.
+type dbusErrorReply struct {
+	Name    string
+	Message string
+}
+
+func (e *dbusErrorReply) Error() string {
+	if e.Message == "" {
+		return "dbus: " + e.Name
+	}
+	return "dbus: " + e.Name + ": " + e.Message
+}
+
+// dbusConn is a connection to a message bus.
+type dbusConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	serial uint32
+	stop   func() bool
+}
+
+// systemBusAddress returns the address of the system bus.
+func systemBusAddress() string {
+	if addr := os.Getenv("DBUS_SYSTEM_BUS_ADDRESS"); addr != "" {
+		return addr
+	}
+	return defaultSystemBusAddress
+}
+
+// dbusSocketPaths returns the unix socket paths of a D-Bus address, in
+// order. Abstract sockets are returned with a leading "@".
+func dbusSocketPaths(address string) []string {
+	var paths []string
+	for _, addr := range strings.Split(address, ";") {
+		transport, params, ok := strings.Cut(addr, ":")
+		if !ok || transport != "unix" {
+			continue
+		}
+		for _, param := range strings.Split(params, ",") {
+			key, value, _ := strings.Cut(param, "=")
+			value = dbusUnescape(value)
+			switch key {
+			case "path":
+				paths = append(paths, value)
+			case "abstract":
+				paths = append(paths, "@"+value)
+			}
+		}
+	}
+	return paths
+}
+
+// dbusUnescape decodes the %XX escapes of a D-Bus address value.
+func dbusUnescape(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// dialDBus connects and authenticates to the bus at address, and registers
+// with it by calling Hello.
+func dialDBus(ctx context.Context, address string) (*dbusConn, error) {
+	var d net.Dialer
+	var conn net.Conn
+	var err error = errNoBus
+	for _, path := range dbusSocketPaths(address) {
+		conn, err = d.DialContext(ctx, "unix", path)
+		if err == nil {
+			break
+		}
+	}
+	if conn == nil {
+		if err != errNoBus {
+			err = fmt.Errorf("%w: %v", errNoBus, err)
+		}
+		return nil, err
+	}
+
+	// Unblock pending reads and writes once ctx is done
+	c := &dbusConn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		stop: context.AfterFunc(ctx, func() { conn.Close() }),
+	}
+	if err := c.auth(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if _, err := c.call("org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "Hello", ""); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// auth performs the EXTERNAL authentication handshake.
+func (c *dbusConn) auth() error {
+	uid := strconv.Itoa(os.Getuid())
+	if _, err := io.WriteString(c.conn, "\x00AUTH EXTERNAL "+hex.EncodeToString([]byte(uid))+"\r\n"); err != nil {
+		return err
+	}
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("dbus: authentication rejected: %s", strings.TrimSpace(line))
+	}
+	_, err = io.WriteString(c.conn, "BEGIN\r\n")
+	return err
+}
+
+func (c *dbusConn) Close() error {
+	c.stop()
+	return c.conn.Close()
+}
+
+// call calls a method and returns the body of its reply. sig is the
+// signature of args.
+func (c *dbusConn) call(dest, path, iface, member, sig string, args ...any) ([]any, error) {
+	c.serial++
+	msg := &dbusMessage{
+		Type:   dbusMethodCall,
+		Serial: c.serial,
+		Fields: map[byte]dbusVariant{
+			dbusFieldPath:        {"o", dbusObjectPath(path)},
+			dbusFieldInterface:   {"s", iface},
+			dbusFieldMember:      {"s", member},
+			dbusFieldDestination: {"s", dest},
+		},
+		Body: args,
+	}
+	if sig != "" {
+		msg.Fields[dbusFieldSignature] = dbusVariant{"g", dbusSignature(sig)}
+	}
+	data, err := encodeDBusMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.conn.Write(data); err != nil {
+		return nil, err
+	}
+
+	for {
+		reply, err := readDBusMessage(c.r)
+		if err != nil {
+			return nil, err
+		}
+		if reply.Type != dbusMethodReturn && reply.Type != dbusError {
+			continue // Signals such as NameAcquired
+		}
+		if reply.field(dbusFieldReplySerial) != strconv.FormatUint(uint64(msg.Serial), 10) {
+			continue
+		}
+		if reply.Type == dbusError {
+			e := &dbusErrorReply{Name: reply.field(dbusFieldErrorName)}
+			if len(reply.Body) > 0 {
+				e.Message, _ = reply.Body[0].(string)
+			}
+			return nil, e
+		}
+		return reply.Body, nil
+	}
+}
+
+// getAllProperties returns the properties of an interface of an object.
+func (c *dbusConn) getAllProperties(dest, path, iface string) (map[string]any, error) {
+	body, err := c.call(dest, path, "org.freedesktop.DBus.Properties", "GetAll", "s", iface)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) != 1 {
+		return nil, parseError("unexpected GetAll reply %v", body)
+	}
+	dict, ok := body[0].(map[any]any)
+	if !ok {
+		return nil, parseError("unexpected GetAll reply %v", body)
+	}
+	props := make(map[string]any, len(dict))
+	for k, v := range dict {
+		name, _ := k.(string)
+		if variant, ok := v.(dbusVariant); ok {
+			v = variant.Value
+		}
+		props[name] = v
+	}
+	return props, nil
+}
+
+// encodeDBusMessage serializes a message in little endian byte order.
+func encodeDBusMessage(m *dbusMessage) ([]byte, error) {
+	sig := ""
+	switch v := m.Fields[dbusFieldSignature].Value.(type) {
+	case dbusSignature:
+		sig = string(v)
+	case string:
+		sig = v
+	}
+	body := &dbusEncoder{order: binary.LittleEndian}
+	if err := body.encodeAll(sig, m.Body); err != nil {
+		return nil, err
+	}
+
+	codes := make([]int, 0, len(m.Fields))
+	for code := range m.Fields {
+		codes = append(codes, int(code))
+	}
+	sort.Ints(codes)
+	fields := make([]any, 0, len(codes))
+	for _, code := range codes {
+		fields = append(fields, []any{byte(code), m.Fields[byte(code)]})
+	}
+
+	e := &dbusEncoder{order: binary.LittleEndian}
+	e.buf.WriteByte('l')
+	e.buf.WriteByte(m.Type)
+	e.buf.WriteByte(m.Flags)
+	e.buf.WriteByte(1) // Protocol version
+	e.uint32(uint32(body.buf.Len()))
+	e.uint32(m.Serial)
+	if err := e.encode("a(yv)", fields); err != nil {
+		return nil, err
+	}
+	e.align(8)
+	e.buf.Write(body.buf.Bytes())
+	return e.buf.Bytes(), nil
+}
+
+// readDBusMessage reads and decodes one message.
+func readDBusMessage(r io.Reader) (*dbusMessage, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+
+	var order binary.ByteOrder
+	switch fixed[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, parseError("invalid D-Bus endianness %q", fixed[0])
+	}
+	bodyLen := order.Uint32(fixed[4:8])
+	fieldsLen := order.Uint32(fixed[12:16])
+	if bodyLen > 1<<27 || fieldsLen > 1<<26 {
+		return nil, parseError("D-Bus message too long")
+	}
+
+	headerLen := 16 + int(fieldsLen)
+	padded := (headerLen + 7) &^ 7
+	rest := make([]byte, padded-16+int(bodyLen))
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, err
+	}
+	data := append(fixed, rest...)
+
+	m := &dbusMessage{
+		Type:   data[1],
+		Flags:  data[2],
+		Serial: order.Uint32(data[8:12]),
+		Fields: make(map[byte]dbusVariant),
+	}
+
+	d := &dbusDecoder{data: data[:headerLen], order: order, pos: 12}
+	fields, err := d.decode("a(yv)")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields.([]any) {
+		field := f.([]any)
+		m.Fields[field[0].(byte)] = field[1].(dbusVariant)
+	}
+
+	if sig, ok := m.Fields[dbusFieldSignature].Value.(string); ok && sig != "" {
+		d = &dbusDecoder{data: data[padded:], order: order}
+		m.Body, err = d.decodeAll(sig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// dbusEncoder serializes values according to D-Bus signatures.
+type dbusEncoder struct {
+	buf   bytes.Buffer
+	order binary.ByteOrder
+}
+
+func (e *dbusEncoder) align(n int) {
+	for e.buf.Len()%n != 0 {
+		e.buf.WriteByte(0)
+	}
+}
+
+func (e *dbusEncoder) uint32(v uint32) {
+	e.align(4)
+	var b [4]byte
+	e.order.PutUint32(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *dbusEncoder) encodeAll(sig string, values []any) error {
+	types, err := splitDBusSignature(sig)
+	if err != nil {
+		return err
+	}
+	if len(types) != len(values) {
+		return fmt.Errorf("dbus: signature %q does not match %d values", sig, len(values))
+	}
+	for i, t := range types {
+		if err := e.encode(t, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *dbusEncoder) encode(sig string, v any) error {
+	bad := func() error { return fmt.Errorf("dbus: cannot encode %T as %q", v, sig) }
+
+	switch sig[0] {
+	case 'y':
+		b, ok := v.(byte)
+		if !ok {
+			return bad()
+		}
+		e.buf.WriteByte(b)
+	case 'b':
+		b, ok := v.(bool)
+		if !ok {
+			return bad()
+		}
+		if b {
+			e.uint32(1)
+		} else {
+			e.uint32(0)
+		}
+	case 'u':
+		u, ok := v.(uint32)
+		if !ok {
+			return bad()
+		}
+		e.uint32(u)
+	case 'i':
+		i, ok := v.(int32)
+		if !ok {
+			return bad()
+		}
+		e.uint32(uint32(i))
+	case 's', 'o':
+		var s string
+		switch x := v.(type) {
+		case string:
+			s = x
+		case dbusObjectPath:
+			s = string(x)
+		default:
+			return bad()
+		}
+		e.uint32(uint32(len(s)))
+		e.buf.WriteString(s)
+		e.buf.WriteByte(0)
+	case 'g':
+		var s string
+		switch x := v.(type) {
+		case string:
+			s = x
+		case dbusSignature:
+			s = string(x)
+		default:
+			return bad()
+		}
+		e.buf.WriteByte(byte(len(s)))
+		e.buf.WriteString(s)
+		e.buf.WriteByte(0)
+	case 'v':
+		variant, ok := v.(dbusVariant)
+		if !ok {
+			return bad()
+		}
+		if err := e.encode("g", variant.Sig); err != nil {
+			return err
+		}
+		return e.encode(variant.Sig, variant.Value)
+	case '(':
+		fields, ok := v.([]any)
+		if !ok {
+			return bad()
+		}
+		e.align(8)
+		return e.encodeAll(sig[1:len(sig)-1], fields)
+	case 'a':
+		elem := sig[1:]
+		e.uint32(0) // Length placeholder
+		lenPos := e.buf.Len() - 4
+		e.align(dbusAlignment(elem[0]))
+		start := e.buf.Len()
+		if elem[0] == '{' {
+			dict, ok := v.(map[string]any)
+			if !ok {
+				return bad()
+			}
+			kv, err := splitDBusSignature(elem[1 : len(elem)-1])
+			if err != nil || len(kv) != 2 {
+				return bad()
+			}
+			keys := make([]string, 0, len(dict))
+			for k := range dict {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				e.align(8)
+				if err := e.encode(kv[0], k); err != nil {
+					return err
+				}
+				if err := e.encode(kv[1], dict[k]); err != nil {
+					return err
+				}
+			}
+		} else {
+			var items []any
+			switch x := v.(type) {
+			case []any:
+				items = x
+			case []string:
+				for _, s := range x {
+					items = append(items, s)
+				}
+			default:
+				return bad()
+			}
+			for _, item := range items {
+				if err := e.encode(elem, item); err != nil {
+					return err
+				}
+			}
+		}
+		e.order.PutUint32(e.buf.Bytes()[lenPos:], uint32(e.buf.Len()-start))
+	default:
+		return fmt.Errorf("dbus: unsupported signature %q", sig)
+	}
+	return nil
+}
+
+// dbusDecoder deserializes values according to D-Bus signatures.
+type dbusDecoder struct {
+	data  []byte
+	order binary.ByteOrder
+	pos   int
+}
+
+func (d *dbusDecoder) align(n int) error {
+	for d.pos%n != 0 {
+		d.pos++
+	}
+	if d.pos > len(d.data) {
+		return parseError("truncated D-Bus message")
+	}
+	return nil
+}
+
+func (d *dbusDecoder) next(n int) ([]byte, error) {
+	if d.pos+n > len(d.data) {
+		return nil, parseError("truncated D-Bus message")
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *dbusDecoder) fixed(n int) ([]byte, error) {
+	if err := d.align(n); err != nil {
+		return nil, err
+	}
+	return d.next(n)
+}
+
+func (d *dbusDecoder) decodeAll(sig string) ([]any, error) {
+	types, err := splitDBusSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, 0, len(types))
+	for _, t := range types {
+		v, err := d.decode(t)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (d *dbusDecoder) decode(sig string) (any, error) {
+	switch sig[0] {
+	case 'y':
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0], nil
+	case 'b':
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		return d.order.Uint32(b) != 0, nil
+	case 'n':
+		b, err := d.fixed(2)
+		if err != nil {
+			return nil, err
+		}
+		return int16(d.order.Uint16(b)), nil
+	case 'q':
+		b, err := d.fixed(2)
+		if err != nil {
+			return nil, err
+		}
+		return d.order.Uint16(b), nil
+	case 'i':
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		return int32(d.order.Uint32(b)), nil
+	case 'u', 'h':
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		return d.order.Uint32(b), nil
+	case 'x':
+		b, err := d.fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		return int64(d.order.Uint64(b)), nil
+	case 't':
+		b, err := d.fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		return d.order.Uint64(b), nil
+	case 'd':
+		b, err := d.fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(d.order.Uint64(b)), nil
+	case 's', 'o':
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		n := uint64(d.order.Uint32(b)) + 1
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, parseError("truncated D-Bus string")
+		}
+		s, err := d.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return string(s[:len(s)-1]), nil
+	case 'g':
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		s, err := d.next(int(b[0]) + 1)
+		if err != nil {
+			return nil, err
+		}
+		return string(s[:len(s)-1]), nil
+	case 'v':
+		s, err := d.decode("g")
+		if err != nil {
+			return nil, err
+		}
+		sig := s.(string)
+		if types, err := splitDBusSignature(sig); err != nil || len(types) != 1 {
+			return nil, parseError("invalid D-Bus variant signature %q", sig)
+		}
+		v, err := d.decode(sig)
+		if err != nil {
+			return nil, err
+		}
+		return dbusVariant{Sig: sig, Value: v}, nil
+	case '(':
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		return d.decodeAll(sig[1 : len(sig)-1])
+	case 'a':
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		n := uint64(d.order.Uint32(b))
+		elem := sig[1:]
+		if err := d.align(dbusAlignment(elem[0])); err != nil {
+			return nil, err
+		}
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, parseError("truncated D-Bus array")
+		}
+		end := d.pos + int(n)
+		if elem[0] == '{' {
+			kv, err := splitDBusSignature(elem[1 : len(elem)-1])
+			if err != nil || len(kv) != 2 {
+				return nil, parseError("invalid D-Bus dict signature %q", sig)
+			}
+			dict := make(map[any]any)
+			for d.pos < end {
+				if err := d.align(8); err != nil {
+					return nil, err
+				}
+				k, err := d.decode(kv[0])
+				if err != nil {
+					return nil, err
+				}
+				v, err := d.decode(kv[1])
+				if err != nil {
+					return nil, err
+				}
+				dict[k] = v
+			}
+			return dict, nil
+		}
+		items := []any{}
+		for d.pos < end {
+			v, err := d.decode(elem)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	}
+	return nil, parseError("unsupported D-Bus signature %q", sig)
+}
+
+// dbusAlignment returns the alignment of a type code.
+func dbusAlignment(code byte) int {
+	switch code {
+	case 'n', 'q':
+		return 2
+	case 'b', 'i', 'u', 'h', 's', 'o', 'a':
+		return 4
+	case 'x', 't', 'd', '(', '{':
+		return 8
+	}
+	return 1
+}
+
+// splitDBusSignature splits a signature into its complete types.
+func splitDBusSignature(sig string) ([]string, error) {
+	var types []string
+	for len(sig) > 0 {
+		n, err := dbusTypeLen(sig)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, sig[:n])
+		sig = sig[n:]
+	}
+	return types, nil
+}
+
+// dbusTypeLen returns the length of the first complete type in sig. Empty
+// structs, which would make arrays of them take no space, and dict entries
+// without a basic key type are rejected.
+func dbusTypeLen(sig string) (int, error) {
+	if sig == "" {
+		return 0, parseError("empty D-Bus signature")
+	}
+	switch sig[0] {
+	case 'a':
+		n, err := dbusTypeLen(sig[1:])
+		return n + 1, err
+	case '(', '{':
+		closing := byte(')')
+		if sig[0] == '{' {
+			closing = '}'
+		}
+		i := 1
+		for i < len(sig) && sig[i] != closing {
+			n, err := dbusTypeLen(sig[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+		if i >= len(sig) {
+			return 0, parseError("unbalanced D-Bus signature %q", sig)
+		}
+		if i == 1 {
+			return 0, parseError("empty D-Bus struct in signature %q", sig)
+		}
+		if sig[0] == '{' && !strings.ContainsRune("ybnqiuxtdhsog", rune(sig[1])) {
+			return 0, parseError("D-Bus dict key of signature %q is not a basic type", sig)
+		}
+		return i + 1, nil
+	case 'y', 'b', 'n', 'q', 'i', 'u', 'x', 't', 'd', 'h', 's', 'o', 'g', 'v':
+		return 1, nil
+	}
+	return 0, parseError("invalid D-Bus signature %q", sig)
+}
@@ -0,0 +1,226 @@
+package keyloc
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeBus is a minimal D-Bus message bus that answers Hello and
+// Properties.GetAll calls on a unix socket.
+type fakeBus struct {
+	address    string
+	properties map[string]map[string]any // by interface
+}
+
+func newFakeBus(t *testing.T, properties map[string]map[string]any) *fakeBus {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bus")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	b := &fakeBus{address: "unix:path=" + path, properties: properties}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *fakeBus) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "\x00AUTH EXTERNAL ") {
+		conn.Write([]byte("REJECTED EXTERNAL\r\n"))
+		return
+	}
+	conn.Write([]byte("OK 0123456789abcdef0123456789abcdef\r\n"))
+	if line, err := r.ReadString('\n'); err != nil || line != "BEGIN\r\n" {
+		return
+	}
+
+	serial := uint32(0)
+	reply := func(call *dbusMessage, typ byte, fields map[byte]dbusVariant, sig string, body ...any) {
+		serial++
+		fields[dbusFieldReplySerial] = dbusVariant{"u", call.Serial}
+		fields[dbusFieldSender] = dbusVariant{"s", "org.freedesktop.DBus"}
+		if sig != "" {
+			fields[dbusFieldSignature] = dbusVariant{"g", dbusSignature(sig)}
+		}
+		data, err := encodeDBusMessage(&dbusMessage{Type: typ, Serial: serial, Fields: fields, Body: body})
+		if err != nil {
+			panic(err)
+		}
+		conn.Write(data)
+	}
+
+	for {
+		call, err := readDBusMessage(r)
+		if err != nil {
+			return
+		}
+		switch call.field(dbusFieldMember) {
+		case "Hello":
+			// A signal arrives before the reply, as with a real bus
+			serial++
+			signal, _ := encodeDBusMessage(&dbusMessage{
+				Type:   dbusSignal,
+				Serial: serial,
+				Fields: map[byte]dbusVariant{
+					dbusFieldPath:      {"o", dbusObjectPath("/org/freedesktop/DBus")},
+					dbusFieldInterface: {"s", "org.freedesktop.DBus"},
+					dbusFieldMember:    {"s", "NameAcquired"},
+					dbusFieldSignature: {"g", dbusSignature("s")},
+				},
+				Body: []any{":1.42"},
+			})
+			conn.Write(signal)
+			reply(call, dbusMethodReturn, map[byte]dbusVariant{}, "s", ":1.42")
+		case "GetAll":
+			props, ok := b.properties[call.Body[0].(string)]
+			if !ok {
+				reply(call, dbusError, map[byte]dbusVariant{dbusFieldErrorName: {"s", "org.freedesktop.DBus.Error.UnknownInterface"}}, "s", "no such interface")
+				continue
+			}
+			dict := make(map[string]any, len(props))
+			for k, v := range props {
+				switch v := v.(type) {
+				case string:
+					dict[k] = dbusVariant{"s", v}
+				case []string:
+					dict[k] = dbusVariant{"as", v}
+				}
+			}
+			reply(call, dbusMethodReturn, map[byte]dbusVariant{}, "a{sv}", dict)
+		default:
+			reply(call, dbusError, map[byte]dbusVariant{dbusFieldErrorName: {"s", "org.freedesktop.DBus.Error.UnknownMethod"}}, "")
+		}
+	}
+}
+
+func TestQueryLocale1(t *testing.T) {
+	bus := newFakeBus(t, map[string]map[string]any{
+		"org.freedesktop.locale1": {
+			"Locale":         []string{"LANG=ko_KR.UTF-8", "LC_TIME=en_GB.UTF-8"},
+			"VConsoleKeymap": "kr",
+			"X11Layout":      "us,kr",
+			"X11Model":       "pc105",
+			"X11Variant":     "dvorak,",
+			"X11Options":     "grp:alt_shift_toggle",
+		},
+	})
+
+	props, err := queryLocale1(context.Background(), bus.address)
+	if err != nil {
+		t.Fatalf("queryLocale1() returned an error: %v", err)
+	}
+
+	expected := &locale1Properties{
+		X11Layout:      "us,kr",
+		X11Variant:     "dvorak,",
+		X11Model:       "pc105",
+		X11Options:     "grp:alt_shift_toggle",
+		VConsoleKeymap: "kr",
+		Locale:         []string{"LANG=ko_KR.UTF-8", "LC_TIME=en_GB.UTF-8"},
+	}
+	if !reflect.DeepEqual(props, expected) {
+		t.Errorf("queryLocale1() = %+v, want %+v", props, expected)
+	}
+	if layouts := props.layouts(); !reflect.DeepEqual(layouts, []x11Layout{{"us", "dvorak"}, {"kr", ""}}) {
+		t.Errorf("layouts() = %v, want [{us dvorak} {kr }]", layouts)
+	}
+
+	// Without X11 layouts, the console keymap is used
+	props = &locale1Properties{VConsoleKeymap: "de-latin1-nodeadkeys"}
+	if layouts := props.layouts(); !reflect.DeepEqual(layouts, []x11Layout{{"de", "nodeadkeys"}}) {
+		t.Errorf("layouts() of a console keymap = %v, want [{de nodeadkeys}]", layouts)
+	}
+}
+
+func TestQueryLocale1Errors(t *testing.T) {
+	bus := newFakeBus(t, nil)
+	_, err := queryLocale1(context.Background(), bus.address)
+	var reply *dbusErrorReply
+	if !errors.As(err, &reply) || reply.Name != "org.freedesktop.DBus.Error.UnknownInterface" {
+		t.Errorf("queryLocale1() error = %v, want an UnknownInterface error reply", err)
+	}
+
+	_, err = queryLocale1(context.Background(), "unix:path="+filepath.Join(t.TempDir(), "missing"))
+	if !errors.Is(err, errNoBus) {
+		t.Errorf("queryLocale1() without a bus error = %v, want %v", err, errNoBus)
+	}
+}
+
+func TestDBusSocketPaths(t *testing.T) {
+	got := dbusSocketPaths("unix:path=/run/dbus/system_bus_socket;tcp:host=localhost,port=1;unix:abstract=/tmp/dbus%2dx,guid=1")
+	expected := []string{"/run/dbus/system_bus_socket", "@/tmp/dbus-x"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("dbusSocketPaths() = %v, want %v", got, expected)
+	}
+}
+
+func TestDBusRoundTrip(t *testing.T) {
+	sig := "ya{sv}(sas)b"
+	values := []any{
+		byte(7),
+		map[string]any{"a": dbusVariant{"u", uint32(1)}, "b": dbusVariant{"as", []string{"x", "y"}}},
+		[]any{"s", []string{"p", "q"}},
+		true,
+	}
+
+	e := &dbusEncoder{order: binary.LittleEndian}
+	if err := e.encodeAll(sig, values); err != nil {
+		t.Fatalf("encodeAll() returned an error: %v", err)
+	}
+	d := &dbusDecoder{data: e.buf.Bytes(), order: binary.LittleEndian}
+	got, err := d.decodeAll(sig)
+	if err != nil {
+		t.Fatalf("decodeAll() returned an error: %v", err)
+	}
+
+	expected := []any{
+		byte(7),
+		map[any]any{"a": dbusVariant{"u", uint32(1)}, "b": dbusVariant{"as", []any{"x", "y"}}},
+		[]any{"s", []any{"p", "q"}},
+		true,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("decodeAll() = %#v, want %#v", got, expected)
+	}
+}
+
+func TestDBusDecodeMalformed(t *testing.T) {
+	tests := []struct {
+		name, sig string
+		data      []byte
+	}{
+		{"array of empty structs", "a()", []byte{8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"dict with a struct key", "a{(s)s}", []byte{0, 0, 0, 0}},
+		{"dict with a variant key", "a{vs}", []byte{0, 0, 0, 0}},
+		{"variant holding an array of empty structs", "v", []byte("\x03a()\x00\x00\x00\x00\x08\x00\x00\x00")},
+		{"string longer than the message", "s", []byte{0xff, 0xff, 0xff, 0xff, 'a', 0}},
+		{"array longer than the message", "as", []byte{0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, tt := range tests {
+		d := &dbusDecoder{data: tt.data, order: binary.LittleEndian}
+		if _, err := d.decodeAll(tt.sig); !errors.Is(err, ErrParse) {
+			t.Errorf("decodeAll() of %s error = %v, want %v", tt.name, err, ErrParse)
+		}
+	}
+}
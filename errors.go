@@ -0,0 +1,95 @@
+package keyloc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var (
+	// ErrNoBackend is returned when none of the providers of a client is available on this system.
+	ErrNoBackend = errors.New("keyloc: no input source provider is available on this system")
+	// ErrCommandNotFound is returned when a provider needs an external command that is not installed.
+	ErrCommandNotFound = errors.New("keyloc: command not found")
+	// ErrParse is returned when the output or configuration read by a provider cannot be understood.
+	ErrParse = errors.New("keyloc: cannot parse input source information")
+	// ErrNoInputSource is returned when the system reports no input source at all.
+	ErrNoInputSource = errors.New("keyloc: no input source is configured")
+	// ErrInvalidTag is returned by ParseTag for input that is not a well-formed language tag.
+	ErrInvalidTag = errors.New("keyloc: invalid language tag")
+)
+
+// errNotRunning is returned by providers that inspect another root
+// filesystem when asked about the state of a running session.
+var errNotRunning = errors.New("keyloc: the active input source is only known on the running system")
+
+// ProviderError records a failed query of a provider.
+type ProviderError struct {
+	// Provider is the name of the failed provider.
+	Provider string
+	// Stderr holds the standard error output of the external command the
+	// provider ran, if any.
+	Stderr string
+	Err    error
+}
+
+func (e *ProviderError) Error() string {
+	msg := "keyloc: provider " + e.Provider
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// providerError wraps err in a *ProviderError for the named provider,
+// unless it already is one.
+func providerError(name string, err error) *ProviderError {
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		if pe.Provider == "" {
+			pe.Provider = name
+		}
+		return pe
+	}
+	return &ProviderError{Provider: name, Err: err}
+}
+
+// parseError returns an error wrapping ErrParse.
+func parseError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrParse, fmt.Sprintf(format, args...))
+}
+
+// runCommand runs an external command on behalf of a provider and returns
+// its standard output. Failures are reported as a *ProviderError that
+// carries the standard error output and wraps ErrCommandNotFound if the
+// command is not installed.
+func runCommand(ctx context.Context, provider, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			err = fmt.Errorf("%w: %s", ErrCommandNotFound, name)
+		} else if ctx.Err() != nil {
+			err = ctx.Err()
+		} else {
+			err = fmt.Errorf("%s: %w", name, err)
+		}
+		return nil, &ProviderError{
+			Provider: provider,
+			Stderr:   strings.TrimSpace(stderr.String()),
+			Err:      err,
+		}
+	}
+	return output, nil
+}
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/lemon-mint/keyloc"
 )
 
 func main() {
 	// Example: Check if a specific language is supported
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
 	lang := "en"
-	supported, err := keyloc.CheckLanguage(lang)
+	supported, err := keyloc.CheckLanguageContext(ctx, lang)
 	if err != nil {
 		fmt.Printf("Error checking language: %v\n", err)
 		return
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/lemon-mint/keyloc"
+)
+
+func main() {
+	// Example: List the input sources along with the keyboard that provides them
+	sources, err := keyloc.GetInputSources()
+	if err != nil {
+		fmt.Printf("Error getting input sources: %v\n", err)
+		return
+	}
+
+	fmt.Println("Configured input sources:")
+	for i, src := range sources {
+		fmt.Printf("%d. %s [%s] id=%s via %s\n", i+1, src.Name, src.Language, src.ID, src.Backend)
+	}
+}
@@ -0,0 +1,236 @@
+package keyloc
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fcitx5InputMethodDirs lists where Fcitx5 addons install their input
+// method descriptions.
+var fcitx5InputMethodDirs = []string{
+	"/usr/share/fcitx5/inputmethod",
+	"/usr/local/share/fcitx5/inputmethod",
+}
+
+// fcitx5ProfilePath returns the path of the user's Fcitx5 profile, which
+// lists the input method groups.
+func fcitx5ProfilePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "fcitx5", "profile")
+}
+
+// fcitx5Group is an input method group of a Fcitx5 profile.
+type fcitx5Group struct {
+	name      string
+	defaultIM string
+	items     []string // Input method names, in the order they are cycled through
+}
+
+// fcitx5Profile is the parsed Fcitx5 profile. Groups are in the user's
+// order; the first one is the default group, which Fcitx5 activates on startup.
+type fcitx5Profile struct {
+	groups []fcitx5Group
+}
+
+// parseFcitx5Profile parses a Fcitx5 profile, such as
+//
+//	[Groups/0]
+//	Name=Default
+//	Default Layout=us
+//	DefaultIM=pinyin
+//
+//	[Groups/0/Items/0]
+//	Name=keyboard-us
+//
+//	[Groups/0/Items/1]
+//	Name=pinyin
+//
+//	[GroupOrder]
+//	0=Default
+func parseFcitx5Profile(data []byte) (*fcitx5Profile, error) {
+	type item struct {
+		index int
+		name  string
+	}
+	groups := make(map[int]*fcitx5Group)
+	items := make(map[int][]item)
+	group := func(i int) *fcitx5Group {
+		if groups[i] == nil {
+			groups[i] = &fcitx5Group{}
+		}
+		return groups[i]
+	}
+
+	sections := parseINI(data)
+	for _, s := range sections {
+		parts := strings.Split(s.name, "/")
+		if parts[0] != "Groups" || len(parts) < 2 {
+			continue
+		}
+		gi, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		switch {
+		case len(parts) == 2:
+			g := group(gi)
+			g.name = s.values["Name"]
+			g.defaultIM = s.values["DefaultIM"]
+		case len(parts) == 4 && parts[2] == "Items":
+			ii, err := strconv.Atoi(parts[3])
+			if err != nil || s.values["Name"] == "" {
+				continue
+			}
+			group(gi)
+			items[gi] = append(items[gi], item{ii, s.values["Name"]})
+		}
+	}
+	if len(groups) == 0 {
+		return nil, parseError("fcitx5 profile has no input method group")
+	}
+
+	indexes := make([]int, 0, len(groups))
+	for i, g := range groups {
+		list := items[i]
+		sort.Slice(list, func(a, b int) bool { return list[a].index < list[b].index })
+		for _, it := range list {
+			g.items = append(g.items, it.name)
+		}
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	// GroupOrder lists group names by position, starting with the default
+	// group. Groups it leaves out keep their section order.
+	var order []string
+	if s, ok := iniLookup(sections, "GroupOrder"); ok {
+		for i := 0; ; i++ {
+			name, ok := s.values[strconv.Itoa(i)]
+			if !ok {
+				break
+			}
+			order = append(order, name)
+		}
+	}
+
+	p := &fcitx5Profile{}
+	used := make(map[int]bool)
+	for _, name := range order {
+		for _, i := range indexes {
+			if !used[i] && groups[i].name == name {
+				p.groups = append(p.groups, *groups[i])
+				used[i] = true
+				break
+			}
+		}
+	}
+	for _, i := range indexes {
+		if !used[i] {
+			p.groups = append(p.groups, *groups[i])
+		}
+	}
+	return p, nil
+}
+
+// defaultGroup returns the group Fcitx5 uses by default.
+func (p *fcitx5Profile) defaultGroup() fcitx5Group {
+	return p.groups[0]
+}
+
+// inputSources returns the input methods of all groups as input sources,
+// those of the default group first, without duplicates. The name of each
+// source carries the group it is in, as in "Mozc [Default]". The default
+// input method of the default group, or else its first one, is the default.
+func (p *fcitx5Profile) inputSources(backend, root string, ims map[string]fcitx5InputMethod) []InputSource {
+	def := p.defaultGroup()
+	defaultIM := def.defaultIM
+	if !slices.Contains(def.items, defaultIM) && len(def.items) > 0 {
+		defaultIM = def.items[0]
+	}
+
+	var sources []InputSource
+	seen := make(map[string]bool)
+	for gi, g := range p.groups {
+		for _, name := range g.items {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			src := fcitx5Source(backend, root, name, ims)
+			if g.name != "" {
+				src.Name += " [" + g.name + "]"
+			}
+			src.Default = gi == 0 && name == defaultIM
+			sources = append(sources, src)
+		}
+	}
+	return sources
+}
+
+// fcitx5InputMethod is the description of a Fcitx5 input method from its
+// inputmethod/<name>.conf file.
+type fcitx5InputMethod struct {
+	name     string
+	langCode string
+}
+
+// readFcitx5InputMethods reads the input method descriptions in dirs, by
+// input method name. Unreadable files are skipped.
+func readFcitx5InputMethods(dirs []string) map[string]fcitx5InputMethod {
+	ims := make(map[string]fcitx5InputMethod)
+	for _, dir := range dirs {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.conf"))
+		for _, path := range paths {
+			name := strings.TrimSuffix(filepath.Base(path), ".conf")
+			if _, ok := ims[name]; ok {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			s, ok := iniLookup(parseINI(data), "InputMethod")
+			if !ok {
+				continue
+			}
+			ims[name] = fcitx5InputMethod{name: s.values["Name"], langCode: s.values["LangCode"]}
+		}
+	}
+	return ims
+}
+
+// fcitx5Source returns a Fcitx5 input method as an input source. The
+// keyboard layouts that Fcitx5 offers as "keyboard-<layout>[-<variant>]"
+// have no description file and are looked up in the XKB rules of root;
+// they are the only ones that are not input methods.
+func fcitx5Source(backend, root, name string, ims map[string]fcitx5InputMethod) InputSource {
+	if im, ok := ims[name]; ok {
+		displayName := im.name
+		if displayName == "" {
+			displayName = name
+		}
+		src := newInputSource(name, im.langCode, displayName, backend)
+		if !strings.HasPrefix(name, "keyboard-") {
+			src.Kind = KindInputMethod
+		}
+		return src
+	}
+
+	if rest, ok := strings.CutPrefix(name, "keyboard-"); ok {
+		layout, variant, _ := strings.Cut(rest, "-")
+		if info, ok := lookupXKBLayout(root, x11Layout{layout, variant}); ok {
+			return newInputSource(name, info.tag(), info.name, backend)
+		}
+		return newInputSource(name, layout, name, backend)
+	}
+	src := newInputSource(name, "", name, backend)
+	src.Kind = KindInputMethod
+	return src
+}
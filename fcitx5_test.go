@@ -0,0 +1,96 @@
+package keyloc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testFcitx5Profile = `[Groups/0]
+# Group Name
+Name=Other
+# Layout
+Default Layout=de-nodeadkeys
+# Default Input Method
+DefaultIM=keyboard-de-nodeadkeys
+
+[Groups/0/Items/0]
+# Name
+Name=keyboard-de-nodeadkeys
+# Layout
+Layout=
+
+[Groups/1]
+Name=Default
+Default Layout=us
+DefaultIM=pinyin
+
+[Groups/1/Items/1]
+Name=mozc
+
+[Groups/1/Items/0]
+Name=keyboard-us
+
+[Groups/1/Items/2]
+Name=pinyin
+
+[GroupOrder]
+0=Default
+1=Other
+`
+
+func TestParseFcitx5Profile(t *testing.T) {
+	profile, err := parseFcitx5Profile([]byte(testFcitx5Profile))
+	if err != nil {
+		t.Fatalf("parseFcitx5Profile() returned an error: %v", err)
+	}
+
+	expected := []fcitx5Group{
+		{name: "Default", defaultIM: "pinyin", items: []string{"keyboard-us", "mozc", "pinyin"}},
+		{name: "Other", defaultIM: "keyboard-de-nodeadkeys", items: []string{"keyboard-de-nodeadkeys"}},
+	}
+	if !reflect.DeepEqual(profile.groups, expected) {
+		t.Errorf("parseFcitx5Profile() groups = %+v, want %+v", profile.groups, expected)
+	}
+	if g := profile.defaultGroup(); g.name != "Default" {
+		t.Errorf("defaultGroup() = %q, want %q", g.name, "Default")
+	}
+
+	if _, err := parseFcitx5Profile([]byte("[GroupOrder]\n")); !errors.Is(err, ErrParse) {
+		t.Errorf("parseFcitx5Profile() without groups error = %v, want %v", err, ErrParse)
+	}
+}
+
+func TestFcitx5Source(t *testing.T) {
+	dir := t.TempDir()
+	confs := map[string]string{
+		"pinyin.conf": "[InputMethod]\nName=Pinyin\nName[zh_CN]=拼音\nIcon=fcitx-pinyin\nLangCode=zh_CN\nAddon=pinyin\n",
+		"mozc.conf":   "[InputMethod]\nName=Mozc\nLangCode=ja\n",
+		"broken.conf": "not a description\n",
+	}
+	for name, data := range confs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ims := readFcitx5InputMethods([]string{dir})
+
+	tests := []struct {
+		im, lang, name string
+		kind           Kind
+	}{
+		{"pinyin", "zh-CN", "Pinyin", KindInputMethod},
+		{"mozc", "ja", "Mozc", KindInputMethod},
+		{"keyboard-us", "en-US", "English (US)", KindKeyboard},
+		{"keyboard-de-nodeadkeys", "de-DE", "German (no dead keys)", KindKeyboard},
+		{"unknown", "", "unknown", KindInputMethod},
+	}
+	for _, test := range tests {
+		src := fcitx5Source("fcitx5", "", test.im, ims)
+		if src.ID != test.im || src.Language != test.lang || src.Name != test.name || src.Kind != test.kind {
+			t.Errorf("fcitx5Source(%q) = %+v, want language %q, name %q and kind %v", test.im, src, test.lang, test.name, test.kind)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+//go:build ignore
+
+// gen_iso639 generates iso639_table.go from the iso-codes project's ISO 639-2 data.
+//
+// Usage: go run gen_iso639.go [path/to/iso_639-2.json]
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+)
+
+type iso639Entry struct {
+	Alpha2        string `json:"alpha_2"`
+	Alpha3        string `json:"alpha_3"`
+	Bibliographic string `json:"bibliographic"`
+}
+
+func main() {
+	path := "/usr/share/iso-codes/json/iso_639-2.json"
+	if len(os.Args) > 1 {
+		path = os.Args[1]
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var doc map[string][]iso639Entry
+	if err := json.Unmarshal(data, &doc); err != nil {
+		log.Fatal(err)
+	}
+
+	table := make(map[string]string)
+	for _, e := range doc["639-2"] {
+		if e.Alpha2 == "" {
+			continue
+		}
+		table[e.Alpha3] = e.Alpha2
+		if e.Bibliographic != "" {
+			table[e.Bibliographic] = e.Alpha2
+		}
+	}
+
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen_iso639.go; DO NOT EDIT.\n\n")
+	buf.WriteString("package keyloc\n\n")
+	buf.WriteString("// iso639Alpha3 maps ISO 639-2 (terminological and bibliographic) codes to ISO 639-1 codes.\n")
+	buf.WriteString("var iso639Alpha3 = map[string]string{\n")
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "\t%q: %q,\n", k, table[k])
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("iso639_table.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,100 @@
+//go:build ignore
+
+// gen_xkb generates xkb_table.go from xkeyboard-config's evdev rules registry.
+//
+// Usage: go run gen_xkb.go [path/to/evdev.xml]
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+type configItem struct {
+	Name        string   `xml:"name"`
+	Description string   `xml:"description"`
+	Countries   []string `xml:"countryList>iso3166Id"`
+	Languages   []string `xml:"languageList>iso639Id"`
+}
+
+type entry struct {
+	lang, region, name string
+}
+
+func entryFromItem(item configItem) entry {
+	e := entry{name: strings.TrimSpace(item.Description)}
+	if len(item.Languages) > 0 {
+		e.lang = strings.TrimSpace(item.Languages[0])
+	}
+	if len(item.Countries) == 1 {
+		e.region = strings.TrimSpace(item.Countries[0])
+	}
+	return e
+}
+
+func main() {
+	path := "/usr/share/X11/xkb/rules/evdev.xml"
+	if len(os.Args) > 1 {
+		path = os.Args[1]
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var registry struct {
+		Layouts []struct {
+			ConfigItem configItem   `xml:"configItem"`
+			Variants   []configItem `xml:"variantList>variant>configItem"`
+		} `xml:"layoutList>layout"`
+	}
+	if err := xml.Unmarshal(data, &registry); err != nil {
+		log.Fatal(err)
+	}
+
+	table := make(map[string]entry)
+	for _, layout := range registry.Layouts {
+		base := entryFromItem(layout.ConfigItem)
+		table[layout.ConfigItem.Name] = base
+		for _, item := range layout.Variants {
+			e := entryFromItem(item)
+			if e.lang == "" {
+				e.lang, e.region = base.lang, base.region
+			}
+			table[layout.ConfigItem.Name+"("+item.Name+")"] = e
+		}
+	}
+
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen_xkb.go; DO NOT EDIT.\n\n")
+	buf.WriteString("package keyloc\n\n")
+	buf.WriteString("// xkbLayouts holds the layouts and variants of xkeyboard-config's evdev\n")
+	buf.WriteString("// rules registry, for systems that do not have it installed.\n")
+	buf.WriteString("var xkbLayouts = map[string]xkbLayout{\n")
+	for _, k := range keys {
+		e := table[k]
+		fmt.Fprintf(&buf, "\t%q: {%q, %q, %q},\n", k, e.lang, e.region, e.name)
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("xkb_table.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,64 @@
+package keyloc
+
+import "strings"
+
+// Keys of the org.gnome.desktop.input-sources settings in the dconf database.
+const (
+	gnomeSourcesKey    = "/org/gnome/desktop/input-sources/sources"
+	gnomeMRUSourcesKey = "/org/gnome/desktop/input-sources/mru-sources"
+)
+
+// gnomeSource is an entry of the GNOME input source list, such as
+// ('xkb', 'us+dvorak') or ('ibus', 'hangul').
+type gnomeSource struct {
+	typ string
+	id  string
+}
+
+// readGnomeSources reads an input source list from the dconf database at
+// path. An unset key is an empty list.
+func readGnomeSources(path, key string) ([]gnomeSource, error) {
+	v, ok, err := readDconfValue(path, key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if v.Sig != "a(ss)" {
+		return nil, parseError("%s has type %q, want a(ss)", key, v.Sig)
+	}
+
+	var sources []gnomeSource
+	for _, item := range v.Value.([]any) {
+		pair := item.([]any)
+		sources = append(sources, gnomeSource{typ: pair[0].(string), id: pair[1].(string)})
+	}
+	return sources, nil
+}
+
+// gnomeInputSources returns GNOME input source entries as input sources.
+// IBus engines are described by the component files in ibusDirs, and XKB
+// layouts by the rules of root.
+func gnomeInputSources(backend, root string, entries []gnomeSource, ibusDirs []string) []InputSource {
+	var engines map[string]ibusEngine
+	sources := make([]InputSource, 0, len(entries))
+	for _, e := range entries {
+		if e.typ == "ibus" && engines == nil {
+			engines = readIBusEngines(ibusDirs)
+		}
+		sources = append(sources, e.inputSource(backend, root, engines))
+	}
+	return sources
+}
+
+// inputSource returns the entry as an input source. XKB layouts are
+// written "layout+variant" by GNOME; their IDs use the XKB notation
+// "layout(variant)" like the other providers.
+func (s gnomeSource) inputSource(backend, root string, engines map[string]ibusEngine) InputSource {
+	switch s.typ {
+	case "xkb":
+		layout, variant, _ := strings.Cut(s.id, "+")
+		return x11LayoutSource(backend, root, x11Layout{layout, variant})
+	case "ibus":
+		return ibusEngineSource(backend, root, s.id, engines)
+	}
+	return newInputSource(s.id, "", s.id, backend)
+}
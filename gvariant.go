@@ -0,0 +1,294 @@
+package keyloc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// gvariantValue is a GVariant value together with its type string.
+type gvariantValue struct {
+	Sig   string
+	Value any
+}
+
+// decodeGVariant decodes a value serialized in GVariant normal form, the
+// format GLib uses for GSettings values. Strings decode to string, arrays
+// and tuples to []any, maybe types to nil or their value, and variants to
+// gvariantValue. Numbers are in byte order order.
+func decodeGVariant(sig string, data []byte, order binary.ByteOrder) (any, error) {
+	if fixed := gvariantFixedSize(sig); fixed > 0 && len(data) != fixed {
+		return nil, parseError("gvariant %q: %d bytes, want %d", sig, len(data), fixed)
+	}
+
+	switch sig[0] {
+	case 'b':
+		return data[0] != 0, nil
+	case 'y':
+		return data[0], nil
+	case 'n':
+		return int16(order.Uint16(data)), nil
+	case 'q':
+		return order.Uint16(data), nil
+	case 'i', 'h':
+		return int32(order.Uint32(data)), nil
+	case 'u':
+		return order.Uint32(data), nil
+	case 'x':
+		return int64(order.Uint64(data)), nil
+	case 't':
+		return order.Uint64(data), nil
+	case 'd':
+		return math.Float64frombits(order.Uint64(data)), nil
+	case 's', 'o', 'g':
+		if len(data) == 0 {
+			return "", nil
+		}
+		if data[len(data)-1] != 0 {
+			return nil, parseError("gvariant string is not terminated")
+		}
+		return string(data[:len(data)-1]), nil
+	case 'v':
+		i := bytes.LastIndexByte(data, 0)
+		if i < 0 {
+			return nil, parseError("gvariant variant has no type")
+		}
+		inner := string(data[i+1:])
+		if n, err := gvariantTypeLen(inner); err != nil || n != len(inner) {
+			return nil, parseError("invalid gvariant type %q", inner)
+		}
+		v, err := decodeGVariant(inner, data[:i], order)
+		if err != nil {
+			return nil, err
+		}
+		return gvariantValue{Sig: inner, Value: v}, nil
+	case 'm':
+		if len(data) == 0 {
+			return nil, nil
+		}
+		if gvariantFixedSize(sig[1:]) == 0 {
+			data = data[:len(data)-1] // Drop the padding byte
+		}
+		return decodeGVariant(sig[1:], data, order)
+	case 'a':
+		return decodeGVariantArray(sig[1:], data, order)
+	case '(', '{':
+		return decodeGVariantTuple(sig, data, order)
+	}
+	return nil, parseError("unsupported gvariant type %q", sig)
+}
+
+// decodeGVariantArray decodes an array of elem. Arrays of variable-size
+// elements end with the end offset of each element.
+func decodeGVariantArray(elem string, data []byte, order binary.ByteOrder) ([]any, error) {
+	list := []any{}
+	if size := gvariantFixedSize(elem); size > 0 {
+		if len(data)%size != 0 {
+			return nil, parseError("gvariant array of %q: %d bytes", elem, len(data))
+		}
+		for i := 0; i < len(data); i += size {
+			v, err := decodeGVariant(elem, data[i:i+size], order)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+
+	if len(data) == 0 {
+		return list, nil
+	}
+	offsetSize := gvariantOffsetSize(len(data))
+	offsetsStart := gvariantOffset(data[len(data)-offsetSize:])
+	if offsetsStart > len(data) || (len(data)-offsetsStart)%offsetSize != 0 {
+		return nil, parseError("gvariant array of %q has invalid offsets", elem)
+	}
+
+	align := gvariantAlignment(elem)
+	start := 0
+	for i := offsetsStart; i < len(data); i += offsetSize {
+		end := gvariantOffset(data[i : i+offsetSize])
+		start = alignUp(start, align)
+		if start > end || end > offsetsStart {
+			return nil, parseError("gvariant array of %q has invalid offsets", elem)
+		}
+		v, err := decodeGVariant(elem, data[start:end], order)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+		start = end
+	}
+	return list, nil
+}
+
+// decodeGVariantTuple decodes a tuple or dict entry. The end offsets of its
+// variable-size members, except the last, are stored backwards at its end.
+func decodeGVariantTuple(sig string, data []byte, order binary.ByteOrder) ([]any, error) {
+	items, err := splitGVariantType(sig[1 : len(sig)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	offsetSize := gvariantOffsetSize(len(data))
+	framing := len(data)
+	pos := 0
+	values := make([]any, 0, len(items))
+	for i, item := range items {
+		pos = alignUp(pos, gvariantAlignment(item))
+		var end int
+		if size := gvariantFixedSize(item); size > 0 {
+			end = pos + size
+		} else if i == len(items)-1 {
+			end = framing
+		} else {
+			framing -= offsetSize
+			if framing < 0 {
+				return nil, parseError("gvariant tuple %q is truncated", sig)
+			}
+			end = gvariantOffset(data[framing : framing+offsetSize])
+		}
+		if pos > end || end > framing {
+			return nil, parseError("gvariant tuple %q has invalid offsets", sig)
+		}
+		v, err := decodeGVariant(item, data[pos:end], order)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		pos = end
+	}
+	return values, nil
+}
+
+// gvariantOffsetSize returns the size of the framing offsets in a
+// container of n bytes.
+func gvariantOffsetSize(n int) int {
+	switch {
+	case n <= math.MaxUint8:
+		return 1
+	case n <= math.MaxUint16:
+		return 2
+	case uint64(n) <= math.MaxUint32:
+		return 4
+	}
+	return 8
+}
+
+// gvariantOffset reads a little-endian framing offset.
+func gvariantOffset(b []byte) int {
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	if v > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(v)
+}
+
+func alignUp(n, align int) int {
+	return (n + align - 1) &^ (align - 1)
+}
+
+// gvariantAlignment returns the alignment of a complete type.
+func gvariantAlignment(sig string) int {
+	switch sig[0] {
+	case 'n', 'q':
+		return 2
+	case 'i', 'u', 'h':
+		return 4
+	case 'x', 't', 'd', 'v':
+		return 8
+	case 'a', 'm':
+		return gvariantAlignment(sig[1:])
+	case '(', '{':
+		align := 1
+		items, _ := splitGVariantType(sig[1 : len(sig)-1])
+		for _, item := range items {
+			align = max(align, gvariantAlignment(item))
+		}
+		return align
+	}
+	return 1
+}
+
+// gvariantFixedSize returns the size of a fixed-size type, or 0 for
+// variable-size types.
+func gvariantFixedSize(sig string) int {
+	switch sig[0] {
+	case 'b', 'y':
+		return 1
+	case 'n', 'q':
+		return 2
+	case 'i', 'u', 'h':
+		return 4
+	case 'x', 't', 'd':
+		return 8
+	case '(', '{':
+		items, err := splitGVariantType(sig[1 : len(sig)-1])
+		if err != nil {
+			return 0
+		}
+		size := 0
+		for _, item := range items {
+			n := gvariantFixedSize(item)
+			if n == 0 {
+				return 0
+			}
+			size = alignUp(size, gvariantAlignment(item)) + n
+		}
+		if size == 0 {
+			return 1 // The unit type
+		}
+		return alignUp(size, gvariantAlignment(sig))
+	}
+	return 0
+}
+
+// splitGVariantType splits a sequence of types into complete types.
+func splitGVariantType(sig string) ([]string, error) {
+	var types []string
+	for len(sig) > 0 {
+		n, err := gvariantTypeLen(sig)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, sig[:n])
+		sig = sig[n:]
+	}
+	return types, nil
+}
+
+// gvariantTypeLen returns the length of the first complete type in sig.
+func gvariantTypeLen(sig string) (int, error) {
+	if sig == "" {
+		return 0, parseError("empty gvariant type")
+	}
+	switch sig[0] {
+	case 'a', 'm':
+		n, err := gvariantTypeLen(sig[1:])
+		return n + 1, err
+	case '(', '{':
+		closing := byte(')')
+		if sig[0] == '{' {
+			closing = '}'
+		}
+		i := 1
+		for i < len(sig) && sig[i] != closing {
+			n, err := gvariantTypeLen(sig[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+		if i >= len(sig) {
+			return 0, parseError("unbalanced gvariant type %q", sig)
+		}
+		return i + 1, nil
+	case 'b', 'y', 'n', 'q', 'i', 'u', 'x', 't', 'd', 'h', 's', 'o', 'g', 'v':
+		return 1, nil
+	}
+	return 0, parseError("invalid gvariant type %q", sig)
+}
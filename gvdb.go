@@ -0,0 +1,153 @@
+package keyloc
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+)
+
+// gvdb is a GVariant database file, the format of dconf databases such as
+// ~/.config/dconf/user.
+type gvdb struct {
+	data  []byte
+	order binary.ByteOrder
+	items []gvdbItem
+}
+
+// gvdbItem is an entry of the root hash table of a gvdb file.
+type gvdbItem struct {
+	parent   uint32
+	key      string
+	typ      byte // 'v' for a value, 'H' for a hash table, 'L' for a list
+	start    uint32
+	end      uint32
+	fullName string
+}
+
+const (
+	gvdbHeaderSize  = 24
+	gvdbItemSize    = 24
+	gvdbNoParent    = 0xffffffff
+	gvdbMaxKeyDepth = 64
+	gvdbSignatureLE = "GVariant"
+	gvdbSignatureBE = "raVGtnai"
+)
+
+// dconfUserDBPath returns the path of the user's dconf database.
+func dconfUserDBPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "dconf", "user")
+}
+
+// readDconfValue reads key, e.g. "/org/gnome/desktop/input-sources/sources",
+// from the dconf database at path. It reports false if the key is not set.
+func readDconfValue(path, key string) (gvariantValue, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return gvariantValue{}, false, err
+	}
+	db, err := parseGVDB(data)
+	if err != nil {
+		return gvariantValue{}, false, err
+	}
+	return db.value(key)
+}
+
+// parseGVDB reads the header and root hash table of a gvdb file.
+func parseGVDB(data []byte) (*gvdb, error) {
+	if len(data) < gvdbHeaderSize {
+		return nil, parseError("gvdb: file too short")
+	}
+	db := &gvdb{data: data}
+	switch string(data[:8]) {
+	case gvdbSignatureLE:
+		db.order = binary.LittleEndian
+	case gvdbSignatureBE:
+		db.order = binary.BigEndian
+	default:
+		return nil, parseError("gvdb: bad signature")
+	}
+
+	start, end := db.order.Uint32(data[16:]), db.order.Uint32(data[20:])
+	table, ok := db.slice(start, end)
+	if !ok || len(table) < 8 {
+		return nil, parseError("gvdb: invalid root table")
+	}
+
+	// The hash table header is followed by the bloom filter and the
+	// buckets, which are only needed for hashed lookups.
+	bloomWords := db.order.Uint32(table) & (1<<27 - 1)
+	buckets := db.order.Uint32(table[4:])
+	itemsStart := 8 + 4*uint64(bloomWords) + 4*uint64(buckets)
+	if itemsStart > uint64(len(table)) {
+		return nil, parseError("gvdb: invalid root table")
+	}
+	for b := table[itemsStart:]; len(b) >= gvdbItemSize; b = b[gvdbItemSize:] {
+		keyStart := db.order.Uint32(b[8:])
+		keySize := db.order.Uint16(b[12:])
+		key, ok := db.slice(keyStart, keyStart+uint32(keySize))
+		if !ok {
+			return nil, parseError("gvdb: invalid key")
+		}
+		db.items = append(db.items, gvdbItem{
+			parent: db.order.Uint32(b[4:]),
+			key:    string(key),
+			typ:    b[14],
+			start:  db.order.Uint32(b[16:]),
+			end:    db.order.Uint32(b[20:]),
+		})
+	}
+
+	// Keys are stored relative to their parent item, so "/org/gnome/" is
+	// "/" + "org/" + "gnome/".
+	for i := range db.items {
+		name, ok := db.fullName(i, 0)
+		if !ok {
+			return nil, parseError("gvdb: invalid key parent")
+		}
+		db.items[i].fullName = name
+	}
+	return db, nil
+}
+
+func (db *gvdb) fullName(i int, depth int) (string, bool) {
+	item := db.items[i]
+	if item.parent == gvdbNoParent {
+		return item.key, true
+	}
+	if depth > gvdbMaxKeyDepth || int(item.parent) >= len(db.items) {
+		return "", false
+	}
+	parent, ok := db.fullName(int(item.parent), depth+1)
+	return parent + item.key, ok
+}
+
+// value returns the value stored under key.
+func (db *gvdb) value(key string) (gvariantValue, bool, error) {
+	for _, item := range db.items {
+		if item.fullName != key || item.typ != 'v' {
+			continue
+		}
+		data, ok := db.slice(item.start, item.end)
+		if !ok {
+			return gvariantValue{}, false, parseError("gvdb: invalid value of %s", key)
+		}
+		v, err := decodeGVariant("v", data, db.order)
+		if err != nil {
+			return gvariantValue{}, false, err
+		}
+		return v.(gvariantValue), true, nil
+	}
+	return gvariantValue{}, false, nil
+}
+
+// slice returns data[start:end] if it is within the file.
+func (db *gvdb) slice(start, end uint32) ([]byte, bool) {
+	if start > end || uint64(end) > uint64(len(db.data)) {
+		return nil, false
+	}
+	return db.data[start:end], true
+}
@@ -0,0 +1,202 @@
+package keyloc
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testSourcesAss is ('xkb', 'us+dvorak'), ('ibus', 'hangul') serialized as
+// a(ss) by GLib.
+const testSourcesAss = "xkb\x00us+dvorak\x00\x04ibus\x00hangul\x00\x05\x0f\x1c"
+
+// buildGVDB writes a gvdb file in byte order order that stores each value
+// (data already serialized as a variant, including its type) under its
+// key. Keys are split into parent items at each '/', the way dconf stores
+// them.
+func buildGVDB(order binary.ByteOrder, values map[string]string) []byte {
+	type item struct {
+		parent int
+		key    string
+		value  string
+	}
+	var items []item
+	index := make(map[string]int)
+	var add func(path string) int
+	add = func(path string) int {
+		if i, ok := index[path]; ok {
+			return i
+		}
+		parent, key := -1, path
+		if i := strings.LastIndex(strings.TrimSuffix(path, "/"), "/"); i >= 0 {
+			parent, key = add(path[:i+1]), path[i+1:]
+		}
+		items = append(items, item{parent: parent, key: key})
+		index[path] = len(items) - 1
+		return len(items) - 1
+	}
+	for key, value := range values {
+		items[add(key)].value = value
+	}
+
+	// Header, root table (no bloom filter, one bucket), then keys and values
+	tableStart := 24
+	tableEnd := tableStart + 12 + 24*len(items)
+	buf := make([]byte, tableEnd)
+	if order == binary.LittleEndian {
+		copy(buf, "GVariant")
+	} else {
+		copy(buf, "raVGtnai")
+	}
+	order.PutUint32(buf[16:], uint32(tableStart))
+	order.PutUint32(buf[20:], uint32(tableEnd))
+	order.PutUint32(buf[tableStart+4:], 1)
+
+	for i, it := range items {
+		b := buf[tableStart+12+24*i:]
+		parent := uint32(0xffffffff)
+		if it.parent >= 0 {
+			parent = uint32(it.parent)
+		}
+		order.PutUint32(b[4:], parent)
+		order.PutUint32(b[8:], uint32(len(buf)))
+		order.PutUint16(b[12:], uint16(len(it.key)))
+		buf = append(buf, it.key...)
+		b = buf[tableStart+12+24*i:]
+		if it.value == "" {
+			b[14] = 'L'
+			continue
+		}
+		b[14] = 'v'
+		for len(buf)%8 != 0 {
+			buf = append(buf, 0)
+		}
+		order.PutUint32(b[16:], uint32(len(buf)))
+		buf = append(buf, it.value...)
+		order.PutUint32(b[20:], uint32(len(buf)))
+	}
+	return buf
+}
+
+func TestParseGVDB(t *testing.T) {
+	// Framing offsets are little-endian in either byte order; only numbers are swapped.
+	scales := map[binary.ByteOrder]string{
+		binary.LittleEndian: "\x02\x00\x00\x00\x00u",
+		binary.BigEndian:    "\x00\x00\x00\x02\x00u",
+	}
+	for order, scale := range scales {
+		data := buildGVDB(order, map[string]string{
+			gnomeSourcesKey:                      testSourcesAss + "\x00a(ss)",
+			"/org/gnome/desktop/interface/scale": scale,
+		})
+
+		db, err := parseGVDB(data)
+		if err != nil {
+			t.Fatalf("parseGVDB(%v) returned an error: %v", order, err)
+		}
+
+		v, ok, err := db.value(gnomeSourcesKey)
+		if err != nil || !ok {
+			t.Fatalf("value(%q) = %v, %v", gnomeSourcesKey, ok, err)
+		}
+		expected := gvariantValue{Sig: "a(ss)", Value: []any{[]any{"xkb", "us+dvorak"}, []any{"ibus", "hangul"}}}
+		if !reflect.DeepEqual(v, expected) {
+			t.Errorf("value(%q) = %#v, want %#v", gnomeSourcesKey, v, expected)
+		}
+
+		v, ok, err = db.value("/org/gnome/desktop/interface/scale")
+		if err != nil || !ok || v.Value != uint32(2) {
+			t.Errorf("value(scale) = %#v, %v, %v, want uint32 2", v, ok, err)
+		}
+
+		if _, ok, err := db.value("/org/gnome/desktop/"); ok || err != nil {
+			t.Errorf("value() of a directory = %v, %v, want not found", ok, err)
+		}
+	}
+
+	for _, data := range [][]byte{nil, []byte("NotGVDB\x00" + strings.Repeat("\x00", 16))} {
+		if _, err := parseGVDB(data); !errors.Is(err, ErrParse) {
+			t.Errorf("parseGVDB(%q) error = %v, want %v", data, err, ErrParse)
+		}
+	}
+}
+
+func TestDecodeGVariant(t *testing.T) {
+	tests := []struct {
+		sig      string
+		data     string
+		expected any
+	}{
+		{"as", "hangul\x00xkb:us::eng\x00\x07\x13", []any{"hangul", "xkb:us::eng"}},
+		{"as", "", []any{}},
+		{"au", "\x01\x00\x00\x00\x02\x00\x00\x00", []any{uint32(1), uint32(2)}},
+		{"(sb)", "ko\x00\x01\x03", []any{"ko", true}},
+		{"(ys)", "\x07ko\x00", []any{byte(7), "ko"}},
+		{"ms", "ko\x00\x00", "ko"},
+		{"ms", "", nil},
+		{"v", "ko\x00\x00s", gvariantValue{Sig: "s", Value: "ko"}},
+	}
+
+	for _, test := range tests {
+		got, err := decodeGVariant(test.sig, []byte(test.data), binary.LittleEndian)
+		if err != nil {
+			t.Errorf("decodeGVariant(%q, %q) returned an error: %v", test.sig, test.data, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("decodeGVariant(%q, %q) = %#v, want %#v", test.sig, test.data, got, test.expected)
+		}
+	}
+
+	for _, test := range []struct{ sig, data string }{
+		{"s", "ko"},
+		{"u", "\x01"},
+		{"as", "ko\x00\x09"},
+		{"v", "ko"},
+	} {
+		if _, err := decodeGVariant(test.sig, []byte(test.data), binary.LittleEndian); !errors.Is(err, ErrParse) {
+			t.Errorf("decodeGVariant(%q, %q) error = %v, want %v", test.sig, test.data, err, ErrParse)
+		}
+	}
+}
+
+func TestReadGnomeSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user")
+	data := buildGVDB(binary.LittleEndian, map[string]string{
+		gnomeSourcesKey:    testSourcesAss + "\x00a(ss)",
+		gnomeMRUSourcesKey: "\x00as", // Wrong type
+	})
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := readGnomeSources(path, gnomeSourcesKey)
+	if err != nil {
+		t.Fatalf("readGnomeSources() returned an error: %v", err)
+	}
+	if expected := []gnomeSource{{"xkb", "us+dvorak"}, {"ibus", "hangul"}}; !reflect.DeepEqual(entries, expected) {
+		t.Errorf("readGnomeSources() = %v, want %v", entries, expected)
+	}
+
+	if _, err := readGnomeSources(path, gnomeMRUSourcesKey); !errors.Is(err, ErrParse) {
+		t.Errorf("readGnomeSources() of a string array error = %v, want %v", err, ErrParse)
+	}
+	if entries, err := readGnomeSources(path, "/org/gnome/desktop/input-sources/unset"); entries != nil || err != nil {
+		t.Errorf("readGnomeSources() of an unset key = %v, %v, want nothing", entries, err)
+	}
+
+	sources := gnomeInputSources("gnome", "", entries, nil)
+	if len(sources) != 2 {
+		t.Fatalf("gnomeInputSources() returned %d sources, want 2", len(sources))
+	}
+	if sources[0].ID != "us(dvorak)" || sources[0].Language != "en-US" || sources[0].Name != "English (Dvorak)" {
+		t.Errorf("gnomeInputSources()[0] = %+v, want us(dvorak) in en-US", sources[0])
+	}
+	if sources[1].ID != "hangul" || sources[1].Backend != "gnome" {
+		t.Errorf("gnomeInputSources()[1] = %+v, want the hangul engine", sources[1])
+	}
+}
@@ -0,0 +1,40 @@
+package keyloc
+
+import "fmt"
+
+// HKL is a decoded Windows keyboard layout handle, such as 0xF0020409 for
+// the US Dvorak layout used with English (United States).
+type HKL struct {
+	// LangID is the language ID of the input language, from the low word,
+	// e.g. 0x0409.
+	LangID uint16
+	// LayoutID is the high word, which identifies the keyboard layout: the
+	// language ID of the layout (0x0409 for US, also when used with another
+	// input language), 0xFnnn for the additional layouts of a language such
+	// as US Dvorak, or 0xEnnn for an IME.
+	LayoutID uint16
+	// Variant is the Layout Id of an additional layout, e.g. 0x0002 for US
+	// Dvorak and 0x0001 for US International, and 0 otherwise.
+	Variant uint16
+	// IME reports whether the handle belongs to an input method editor.
+	IME bool
+}
+
+// decodeHKL splits a keyboard layout handle into its parts. Only the low 32
+// bits of a handle are significant.
+func decodeHKL(hkl uint32) HKL {
+	h := HKL{LangID: uint16(hkl), LayoutID: uint16(hkl >> 16)}
+	switch h.LayoutID >> 12 {
+	case 0xe:
+		h.IME = true
+	case 0xf:
+		h.Variant = h.LayoutID & 0x0fff
+	}
+	return h
+}
+
+// String returns the handle in the eight hex digit form used as the ID of
+// Windows input sources, e.g. "F0020409".
+func (h HKL) String() string {
+	return fmt.Sprintf("%04X%04X", h.LayoutID, h.LangID)
+}
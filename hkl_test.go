@@ -0,0 +1,33 @@
+package keyloc
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDecodeHKL(t *testing.T) {
+	tests := []struct {
+		hkl      uint32
+		expected HKL
+	}{
+		{0x04090409, HKL{LangID: 0x0409, LayoutID: 0x0409}},
+		// German input language with the US layout
+		{0x04090407, HKL{LangID: 0x0407, LayoutID: 0x0409}},
+		// US Dvorak and US International
+		{0xf0020409, HKL{LangID: 0x0409, LayoutID: 0xf002, Variant: 0x0002}},
+		{0xf0010409, HKL{LangID: 0x0409, LayoutID: 0xf001, Variant: 0x0001}},
+		// Microsoft IME for Japanese
+		{0xe0010411, HKL{LangID: 0x0411, LayoutID: 0xe001, IME: true}},
+		{0x04120412, HKL{LangID: 0x0412, LayoutID: 0x0412}},
+		{0, HKL{}},
+	}
+	for _, tt := range tests {
+		got := decodeHKL(tt.hkl)
+		if got != tt.expected {
+			t.Errorf("decodeHKL(%#08x) = %+v, want %+v", tt.hkl, got, tt.expected)
+		}
+		if s, want := got.String(), fmt.Sprintf("%08X", tt.hkl); s != want {
+			t.Errorf("decodeHKL(%#08x).String() = %q, want %q", tt.hkl, s, want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package keyloc
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ibusComponentDirs lists where IBus engines install their component descriptions.
+var ibusComponentDirs = []string{
+	"/usr/share/ibus/component",
+	"/usr/local/share/ibus/component",
+}
+
+// ibusEngine describes an IBus engine from its component XML.
+type ibusEngine struct {
+	Name     string `xml:"name"`
+	Language string `xml:"language"`
+	Layout   string `xml:"layout"`
+	LongName string `xml:"longname"`
+}
+
+// readIBusEngines reads the engines described by the component XML files
+// in dirs, by engine name. Unreadable files are skipped.
+func readIBusEngines(dirs []string) map[string]ibusEngine {
+	engines := make(map[string]ibusEngine)
+	for _, dir := range dirs {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.xml"))
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			list, err := parseIBusComponent(data)
+			if err != nil {
+				continue
+			}
+			for _, e := range list {
+				if _, ok := engines[e.Name]; !ok {
+					engines[e.Name] = e
+				}
+			}
+		}
+	}
+	return engines
+}
+
+// parseIBusComponent returns the engines listed in an IBus component XML
+// file. Components that list their engines by running a program
+// (<engines exec="...">) yield no engines.
+func parseIBusComponent(data []byte) ([]ibusEngine, error) {
+	var component struct {
+		Engines []ibusEngine `xml:"engines>engine"`
+	}
+	if err := xml.Unmarshal(data, &component); err != nil {
+		return nil, parseError("ibus component: %v", err)
+	}
+	return component.Engines, nil
+}
+
+// ibusRunning reports whether an IBus daemon serves the user's session:
+// its address is in the environment, or the daemon has written it to the
+// bus directory of the user's IBus configuration.
+func ibusRunning() bool {
+	if os.Getenv("IBUS_ADDRESS") != "" {
+		return true
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return false
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "ibus", "bus"))
+	return err == nil && len(entries) > 0
+}
+
+// ibusPreloadEnginesKey is the preload-engines setting of IBus in the dconf database.
+const ibusPreloadEnginesKey = "/desktop/ibus/general/preload-engines"
+
+// ibusPreloadEngines returns the engines the user has enabled, from the
+// preload-engines setting of IBus. It is read from the user's dconf
+// database, or with gsettings or dconf if it is not set there.
+func ibusPreloadEngines(ctx context.Context, provider string) ([]string, error) {
+	if names, ok, err := readIBusPreloadEngines(dconfUserDBPath()); err == nil && ok {
+		return names, nil
+	}
+
+	output, err := runCommand(ctx, provider, "gsettings", "get", "org.freedesktop.ibus.general", "preload-engines")
+	if err != nil {
+		// gsettings fails when the IBus schema is not installed; the
+		// value may still be in the dconf database.
+		var derr error
+		output, derr = runCommand(ctx, provider, "dconf", "read", ibusPreloadEnginesKey)
+		if derr != nil {
+			if errors.Is(derr, ErrCommandNotFound) {
+				return nil, err
+			}
+			return nil, derr
+		}
+	}
+	return parseGVariantStrings(string(output))
+}
+
+// readIBusPreloadEngines reads the preload-engines setting from the dconf
+// database at path. It reports false if the setting is not there.
+func readIBusPreloadEngines(path string) ([]string, bool, error) {
+	v, ok, err := readDconfValue(path, ibusPreloadEnginesKey)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if v.Sig != "as" {
+		return nil, false, parseError("%s has type %q, want as", ibusPreloadEnginesKey, v.Sig)
+	}
+	var names []string
+	for _, name := range v.Value.([]any) {
+		names = append(names, name.(string))
+	}
+	return names, true, nil
+}
+
+// parseGVariantStrings parses a string array in GVariant text format, as
+// printed by gsettings and dconf, e.g. "['xkb:us::eng', 'hangul']" or
+// "@as []". Empty output, which dconf prints for an unset key, is an empty list.
+func parseGVariantStrings(s string) ([]string, error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "@as"))
+	if s == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return nil, parseError("not a GVariant string array: %q", s)
+	}
+	s = s[1 : len(s)-1]
+
+	var list []string
+	for {
+		s = strings.TrimLeft(s, " \t\n,")
+		if s == "" {
+			return list, nil
+		}
+		quote := s[0]
+		if quote != '\'' && quote != '"' {
+			return nil, parseError("not a GVariant string: %q", s)
+		}
+		var b strings.Builder
+		i := 1
+		for ; i < len(s) && s[i] != quote; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+			}
+			b.WriteByte(s[i])
+		}
+		if i == len(s) {
+			return nil, parseError("unterminated GVariant string: %q", s)
+		}
+		list = append(list, b.String())
+		s = s[i+1:]
+	}
+}
+
+// ibusEngineSource returns an IBus engine as an input source. Engines
+// without a component description, or with the placeholder language
+// "other", get their language from the engine name where it carries one,
+// as in "xkb:us::eng" or "m17n:hi:inscript", or from their layout in the
+// XKB rules of root. Engines other than the "xkb:" ones are input methods.
+func ibusEngineSource(backend, root, name string, engines map[string]ibusEngine) InputSource {
+	e, ok := engines[name]
+	lang := e.Language
+	if !ok || lang == "" || lang == "other" {
+		lang = ""
+		if parts := strings.Split(name, ":"); len(parts) >= 4 && parts[0] == "xkb" {
+			lang = strings.Split(parts[3], ",")[0]
+		} else if len(parts) >= 2 && parts[0] == "m17n" {
+			lang = parts[1]
+		}
+		if lang == "" || lang == "t" {
+			// m17n uses "t" for engines that are not tied to a language
+			if layout := e.Layout; layout != "" && layout != "default" {
+				if info, ok := lookupXKBLayout(root, x11Layout{layout: layout}); ok {
+					lang = info.tag()
+				}
+			}
+		}
+	}
+
+	displayName := e.LongName
+	if displayName == "" {
+		displayName = name
+	}
+	src := newInputSource(name, lang, displayName, backend)
+	if !strings.HasPrefix(name, "xkb:") {
+		src.Kind = KindInputMethod
+	}
+	return src
+}
@@ -0,0 +1,111 @@
+package keyloc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testIBusHangul = `<?xml version="1.0" encoding="utf-8"?>
+<component>
+	<name>org.freedesktop.IBus.Hangul</name>
+	<description>Korean Component</description>
+	<exec>/usr/libexec/ibus-engine-hangul --ibus</exec>
+	<engines>
+		<engine>
+			<name>hangul</name>
+			<language>ko</language>
+			<license>GPL</license>
+			<layout>kr</layout>
+			<longname>Korean</longname>
+			<description>Korean Input Method</description>
+			<rank>99</rank>
+		</engine>
+	</engines>
+</component>
+`
+
+const testIBusSimple = `<?xml version="1.0" encoding="utf-8"?>
+<component>
+	<name>org.freedesktop.IBus.Simple</name>
+	<engines>
+		<engine>
+			<name>xkb:us::eng</name>
+			<language>en</language>
+			<layout>us</layout>
+			<longname>English (US)</longname>
+		</engine>
+		<engine>
+			<name>xkb:de::ger</name>
+			<language>other</language>
+			<layout>de</layout>
+			<longname>German</longname>
+		</engine>
+	</engines>
+</component>
+`
+
+func TestParseGVariantStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"['xkb:us::eng', 'hangul']\n", []string{"xkb:us::eng", "hangul"}},
+		{`["it's", 'a\'b']`, []string{"it's", "a'b"}},
+		{"@as []\n", nil},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		got, err := parseGVariantStrings(test.input)
+		if err != nil {
+			t.Errorf("parseGVariantStrings(%q) returned an error: %v", test.input, err)
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("parseGVariantStrings(%q) = %q, want %q", test.input, got, test.expected)
+		}
+	}
+
+	for _, input := range []string{"uint32 5", "['open"} {
+		if _, err := parseGVariantStrings(input); !errors.Is(err, ErrParse) {
+			t.Errorf("parseGVariantStrings(%q) error = %v, want %v", input, err, ErrParse)
+		}
+	}
+}
+
+func TestIBusEngineSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hangul.xml"), []byte(testIBusHangul), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "simple.xml"), []byte(testIBusSimple), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.xml"), []byte("<component><engines>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	engines := readIBusEngines([]string{dir, filepath.Join(dir, "missing")})
+	if len(engines) != 3 {
+		t.Fatalf("readIBusEngines() found %d engines, want 3", len(engines))
+	}
+
+	tests := []struct {
+		engine, lang, name string
+		kind               Kind
+	}{
+		{"hangul", "ko", "Korean", KindInputMethod},
+		{"xkb:us::eng", "en", "English (US)", KindKeyboard},
+		{"xkb:de::ger", "de", "German", KindKeyboard},
+		{"m17n:hi:inscript", "hi", "m17n:hi:inscript", KindInputMethod},
+		{"mozc-jp", "", "mozc-jp", KindInputMethod},
+	}
+	for _, test := range tests {
+		src := ibusEngineSource("ibus", "", test.engine, engines)
+		if src.ID != test.engine || src.Language != test.lang || src.Name != test.name || src.Backend != "ibus" || src.Kind != test.kind {
+			t.Errorf("ibusEngineSource(%q) = %+v, want language %q, name %q and kind %v", test.engine, src, test.lang, test.name, test.kind)
+		}
+	}
+}
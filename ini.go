@@ -0,0 +1,49 @@
+package keyloc
+
+import "strings"
+
+// iniSection is a section of an INI-style configuration file.
+type iniSection struct {
+	name   string
+	values map[string]string
+}
+
+// parseINI parses an INI-style configuration file, as written by Fcitx5
+// and KDE, into its sections in file order. Keys before the first section
+// header belong to a section with an empty name. Comment lines start with
+// '#' or ';'.
+func parseINI(data []byte) []iniSection {
+	var sections []iniSection
+	current := -1
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			sections = append(sections, iniSection{name: line[1 : len(line)-1], values: make(map[string]string)})
+			current = len(sections) - 1
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if current < 0 {
+			sections = append(sections, iniSection{values: make(map[string]string)})
+			current = 0
+		}
+		sections[current].values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return sections
+}
+
+// iniLookup returns the first section with the given name.
+func iniLookup(sections []iniSection, name string) (iniSection, bool) {
+	for _, s := range sections {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return iniSection{}, false
+}
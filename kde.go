@@ -0,0 +1,74 @@
+package keyloc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// kxkbrcPath returns the path of the KDE Plasma keyboard configuration.
+func kxkbrcPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "kxkbrc")
+}
+
+// kxkbrc is the [Layout] section of the KDE Plasma keyboard configuration.
+type kxkbrc struct {
+	// use is false when Plasma leaves the layouts to the system configuration.
+	use          bool
+	layouts      []x11Layout
+	displayNames []string // Labels chosen by the user, by layout; empty for the default
+}
+
+// parseKxkbrc parses a kxkbrc file, such as
+//
+//	[Layout]
+//	DisplayNames=,DE
+//	LayoutList=us,de
+//	Use=true
+//	VariantList=,nodeadkeys
+//
+// A kxkbrc without a [Layout] section, as Plasma leaves it until the user
+// configures layouts, uses the system layouts.
+func parseKxkbrc(data []byte) *kxkbrc {
+	s, ok := iniLookup(parseINI(data), "Layout")
+	if !ok {
+		return &kxkbrc{}
+	}
+
+	// Plasma writes Use=true once the user configures layouts; a missing
+	// key means the defaults, which use the system layouts.
+	cfg := &kxkbrc{use: strings.EqualFold(s.values["Use"], "true")}
+	cfg.layouts = splitX11Layouts(s.values["LayoutList"], s.values["VariantList"])
+	names := strings.Split(s.values["DisplayNames"], ",")
+	for i := range cfg.layouts {
+		name := ""
+		if i < len(names) {
+			name = strings.TrimSpace(names[i])
+		}
+		cfg.displayNames = append(cfg.displayNames, name)
+	}
+	return cfg
+}
+
+// inputSources returns the configured layouts as input sources, labelled
+// with their display names where the user set one, described by the XKB
+// rules of root. It returns nothing when Plasma does not manage the
+// layouts.
+func (cfg *kxkbrc) inputSources(backend, root string) []InputSource {
+	if !cfg.use {
+		return nil
+	}
+	sources := make([]InputSource, 0, len(cfg.layouts))
+	for i, l := range cfg.layouts {
+		src := x11LayoutSource(backend, root, l)
+		if cfg.displayNames[i] != "" {
+			src.Name = cfg.displayNames[i]
+		}
+		sources = append(sources, src)
+	}
+	return sources
+}
@@ -0,0 +1,42 @@
+package keyloc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKxkbrc(t *testing.T) {
+	cfg := parseKxkbrc([]byte("[$Version]\nupdate_info=kxkb_variants.upd:split-variants\n\n[Layout]\nDisplayNames=,DE\nLayoutList=us,de,ara\nOptions=grp:alt_shift_toggle\nResetOldOptions=true\nUse=true\nVariantList=,nodeadkeys,qwerty\n"))
+	expected := &kxkbrc{
+		use:          true,
+		layouts:      []x11Layout{{"us", ""}, {"de", "nodeadkeys"}, {"ara", "qwerty"}},
+		displayNames: []string{"", "DE", ""},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("parseKxkbrc() = %+v, want %+v", cfg, expected)
+	}
+
+	sources := cfg.inputSources("plasma", "")
+	var got [][3]string
+	for _, src := range sources {
+		got = append(got, [3]string{src.ID, src.Language, src.Name})
+	}
+	want := [][3]string{
+		{"us", "en-US", "English (US)"},
+		{"de(nodeadkeys)", "de-DE", "DE"},
+		{"ara(qwerty)", "ar", "Arabic (QWERTY)"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("inputSources() = %v, want %v", got, want)
+	}
+
+	cfg = parseKxkbrc([]byte("[Layout]\nLayoutList=us,de\nUse=false\n"))
+	if sources := cfg.inputSources("plasma", ""); len(sources) != 0 {
+		t.Errorf("inputSources() with Use=false = %v, want none", sources)
+	}
+
+	// Plasma has no [Layout] section until the user configures layouts
+	if sources := parseKxkbrc([]byte("[$Version]\nupdate_info=kxkb.upd:remove-empty-lists\n")).inputSources("plasma", ""); len(sources) != 0 {
+		t.Errorf("inputSources() without [Layout] = %v, want none", sources)
+	}
+}
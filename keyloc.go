@@ -1,33 +1,229 @@
 package keyloc
 
-import "strings"
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// InputSource describes a single keyboard layout or input method reported by the system.
+type InputSource struct {
+	// ID is the raw identifier used by the operating system, e.g. an X11
+	// layout such as "us", a macOS bundle ID such as
+	// "com.apple.inputmethod.Korean.2SetKorean", or a Windows HKL such as "04090409".
+	ID string
+	// Language is the BCP 47 language tag of the source, e.g. "en" or "zh-CN".
+	Language string
+	// Script is the ISO 15924 script subtag of Language, if known (e.g. "Hant").
+	Script string
+	// Region is the ISO 3166-1 or UN M.49 region subtag of Language, if known (e.g. "CN").
+	Region string
+	// Name is a human-readable name of the source, e.g. "English (US)".
+	Name string
+	// Backend is the name of the mechanism that reported the source, e.g. "locale1".
+	Backend string
+	// HKL is the decoded keyboard layout handle of a source reported by a
+	// Windows backend, telling apart layouts of the same language such as
+	// US and US Dvorak. It is the zero HKL on other systems.
+	HKL HKL
+	// Kind tells what the source is: a keyboard layout, an input method, or
+	// a language the system knows of for another reason.
+	Kind Kind
+	// Index is the position of the source among those of its backend that
+	// are of the kinds the client reports, in the order the operating
+	// system keeps them: the XKB group, the Windows preload order or the
+	// order of the enabled macOS input sources.
+	Index int
+	// Default reports whether the backend uses the source by default, e.g.
+	// the first XKB group or the Windows default input language. Backends
+	// that do not single one out default to their first source.
+	Default bool
+	// Confidence tells how reliably Language is known.
+	Confidence Confidence
+	// Primary reports whether the source is the default of the first
+	// backend that answered. Exactly one source of a non-empty result is
+	// primary, making it the natural choice for language-dependent
+	// defaults such as a spellchecker.
+	Primary bool
+}
+
+// Confidence is how reliably the language of an input source is known.
+type Confidence int
+
+const (
+	// ConfidenceHigh means the language was reported by the system or
+	// looked up in a table of known identifiers.
+	ConfidenceHigh Confidence = iota
+	// ConfidenceLow means the language was guessed from words in the
+	// identifier of the source, e.g. "russian" in a layout name.
+	ConfidenceLow
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case ConfidenceHigh:
+		return "high"
+	case ConfidenceLow:
+		return "low"
+	}
+	return fmt.Sprintf("Confidence(%d)", int(c))
+}
+
+// Kind is the kind of an input source. Clients report only keyboard
+// layouts and input methods unless configured with WithKinds.
+type Kind int
+
+const (
+	// KindKeyboard is a keyboard layout, e.g. an XKB layout or a Windows
+	// keyboard layout.
+	KindKeyboard Kind = iota
+	// KindInputMethod is an input method that composes text, e.g. an IBus
+	// engine or a macOS input mode such as Korean 2-Set.
+	KindInputMethod
+	// KindPreferredUILanguage is a language the user prefers for the user
+	// interface, e.g. from macOS AppleLanguages.
+	KindPreferredUILanguage
+	// KindSpeechVoice is the language of an installed text-to-speech voice.
+	KindSpeechVoice
+)
+
+// defaultKinds are the kinds of input sources a client reports by default.
+var defaultKinds = []Kind{KindKeyboard, KindInputMethod}
+
+func (k Kind) String() string {
+	switch k {
+	case KindKeyboard:
+		return "keyboard"
+	case KindInputMethod:
+		return "input method"
+	case KindPreferredUILanguage:
+		return "preferred UI language"
+	case KindSpeechVoice:
+		return "speech voice"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// newInputSource builds an InputSource, canonicalizing the language tag and
+// filling Script and Region from it.
+func newInputSource(id, lang, name, backend string) InputSource {
+	src := InputSource{
+		ID:       id,
+		Language: lang,
+		Name:     name,
+		Backend:  backend,
+	}
+	if t, err := ParseTag(lang); err == nil {
+		src.Language = t.String()
+		src.Script = t.Script
+		src.Region = t.Region
+	}
+	return src
+}
 
 // normalizeLangCode converts a language tag into a consistent, basic format.
-// e.g., "en-US", "en_GB", "EN" all become "en".
+// e.g., "en-US", "en_GB", "EN" all become "en", and "iw" or "heb" become "he".
 func normalizeLangCode(lang string) string {
+	if t, err := ParseTag(lang); err == nil && t.Language != "" {
+		return t.Language
+	}
 	lower := strings.ToLower(lang)
 	normalized := strings.ReplaceAll(lower, "_", "-")
 	parts := strings.Split(normalized, "-")
 	return parts[0]
 }
 
-func CheckLanguage(lang string) (bool, error) {
-	langs, err := getLanguages()
-	if err != nil {
-		return false, err
-	}
+// matchMode selects how strictly a requested language must match an input source.
+type matchMode int
 
-	normalizedInput := normalizeLangCode(lang)
+const (
+	matchLanguage matchMode = iota // primary language only
+	matchScript                    // language and script
+	matchExact                     // language, script, region and variants
+)
 
-	for _, l := range langs {
-		if normalizeLangCode(l) == normalizedInput {
-			return true, nil
+// matchTag reports whether have, the language of an input source, matches want under mode.
+func matchTag(have string, want Tag, mode matchMode) bool {
+	h, err := ParseTag(have)
+	if err != nil {
+		return mode == matchLanguage && normalizeLangCode(have) == want.Language
+	}
+	if h.Language != want.Language {
+		return false
+	}
+	if mode >= matchScript && h.MaximizedScript() != want.MaximizedScript() {
+		return false
+	}
+	if mode >= matchExact {
+		if h.Region != want.Region || strings.Join(h.Variants, "-") != strings.Join(want.Variants, "-") {
+			return false
 		}
 	}
-	return false, nil
+	return true
+}
+
+// CheckLanguage reports whether a keyboard for the primary language of lang
+// is available, e.g. "en-US" matches any English keyboard.
+func CheckLanguage(lang string) (bool, error) {
+	return CheckLanguageContext(context.Background(), lang)
+}
+
+// CheckLanguageContext is like CheckLanguage but gives up when ctx is done.
+func CheckLanguageContext(ctx context.Context, lang string) (bool, error) {
+	return New().CheckLanguage(ctx, lang)
+}
+
+// CheckLanguageWithScript reports whether a keyboard for the language and
+// script of lang is available. Scripts that are not given are inferred, so
+// "zh-TW" matches "zh-Hant" but not "zh-CN", and "sr" matches "sr-Cyrl" but
+// not "sr-Latn".
+func CheckLanguageWithScript(lang string) (bool, error) {
+	return CheckLanguageWithScriptContext(context.Background(), lang)
+}
+
+// CheckLanguageWithScriptContext is like CheckLanguageWithScript but gives up when ctx is done.
+func CheckLanguageWithScriptContext(ctx context.Context, lang string) (bool, error) {
+	return New().CheckLanguageWithScript(ctx, lang)
+}
+
+// CheckLanguageExact reports whether a keyboard for exactly lang is
+// available: language, script, region and variants must all match, so
+// "pt-BR" does not match "pt-PT" or "pt".
+func CheckLanguageExact(lang string) (bool, error) {
+	return CheckLanguageExactContext(context.Background(), lang)
+}
+
+// CheckLanguageExactContext is like CheckLanguageExact but gives up when ctx is done.
+func CheckLanguageExactContext(ctx context.Context, lang string) (bool, error) {
+	return New().CheckLanguageExact(ctx, lang)
 }
 
 // GetLanguages returns the list of supported keyboard languages or input sources on the system.
 func GetLanguages() ([]string, error) {
-	return getLanguages()
+	return GetLanguagesContext(context.Background())
+}
+
+// GetLanguagesContext is like GetLanguages but gives up when ctx is done.
+func GetLanguagesContext(ctx context.Context) ([]string, error) {
+	return New().Languages(ctx)
+}
+
+// GetInputSources returns the keyboard layouts and input methods configured on the system.
+func GetInputSources() ([]InputSource, error) {
+	return GetInputSourcesContext(context.Background())
+}
+
+// GetInputSourcesContext is like GetInputSources but gives up when ctx is done.
+func GetInputSourcesContext(ctx context.Context) ([]InputSource, error) {
+	return New().InputSources(ctx)
+}
+
+// CurrentInputSource returns the input source that is active right now.
+func CurrentInputSource() (InputSource, error) {
+	return CurrentInputSourceContext(context.Background())
+}
+
+// CurrentInputSourceContext is like CurrentInputSource but gives up when ctx is done.
+func CurrentInputSourceContext(ctx context.Context) (InputSource, error) {
+	return New().CurrentInputSource(ctx)
 }
@@ -2,191 +2,141 @@
 package keyloc
 
 import (
+	"context"
+	"os"
 	"os/exec"
 	"regexp"
-	"strings"
 )
 
-// mapIdentifierToLangCode maps common macOS input source identifiers to language codes.
-func mapIdentifierToLangCode(identifier string) string {
-	lowerIdentifier := strings.ToLower(identifier)
-	switch {
-	case strings.Contains(lowerIdentifier, "korean"), strings.Contains(lowerIdentifier, "hangul"):
-		return "ko"
-	case strings.Contains(lowerIdentifier, "u.s."), strings.Contains(lowerIdentifier, "abc"), strings.Contains(lowerIdentifier, "english"):
-		return "en"
-	case strings.Contains(lowerIdentifier, "russian"), strings.Contains(lowerIdentifier, "cyrillic"):
-		return "ru"
-	case strings.Contains(lowerIdentifier, "japanese"), strings.Contains(lowerIdentifier, "kana"), strings.Contains(lowerIdentifier, "romaji"):
-		return "ja"
-	case strings.Contains(lowerIdentifier, "french"):
-		return "fr"
-	case strings.Contains(lowerIdentifier, "german"):
-		return "de"
-	case strings.Contains(lowerIdentifier, "spanish"):
-		return "es"
-	case strings.Contains(lowerIdentifier, "chinese"), strings.Contains(lowerIdentifier, "pinyin"), strings.Contains(lowerIdentifier, "zhuyin"), strings.Contains(lowerIdentifier, "cangjie"):
-		return "zh"
-	case strings.Contains(lowerIdentifier, "italian"):
-		return "it"
-	case strings.Contains(lowerIdentifier, "portuguese"):
-		return "pt"
-	case strings.Contains(lowerIdentifier, "dutch"):
-		return "nl"
-	case strings.Contains(lowerIdentifier, "swedish"):
-		return "sv"
-	case strings.Contains(lowerIdentifier, "danish"):
-		return "da"
-	case strings.Contains(lowerIdentifier, "norwegian"):
-		return "no"
-	case strings.Contains(lowerIdentifier, "finnish"):
-		return "fi"
-	case strings.Contains(lowerIdentifier, "polish"):
-		return "pl"
-	case strings.Contains(lowerIdentifier, "turkish"):
-		return "tr"
-	case strings.Contains(lowerIdentifier, "arabic"):
-		return "ar"
-	case strings.Contains(lowerIdentifier, "hebrew"):
-		return "he"
-	case strings.Contains(lowerIdentifier, "greek"):
-		return "el"
-	case strings.Contains(lowerIdentifier, "thai"):
-		return "th"
-	case strings.Contains(lowerIdentifier, "vietnamese"):
-		return "vi"
-	case strings.Contains(lowerIdentifier, "hindi"):
-		return "hi"
-	case strings.Contains(lowerIdentifier, "bengali"):
-		return "bn"
-	case strings.Contains(lowerIdentifier, "punjabi"):
-		return "pa"
-	case strings.Contains(lowerIdentifier, "gujarati"):
-		return "gu"
-	case strings.Contains(lowerIdentifier, "tamil"):
-		return "ta"
-	case strings.Contains(lowerIdentifier, "telugu"):
-		return "te"
-	case strings.Contains(lowerIdentifier, "kannada"):
-		return "kn"
-	case strings.Contains(lowerIdentifier, "malayalam"):
-		return "ml"
-	case strings.Contains(lowerIdentifier, "indonesian"):
-		return "id"
-	case strings.Contains(lowerIdentifier, "malay"):
-		return "ms"
-	case strings.Contains(lowerIdentifier, "filipino"):
-		return "fil"
-	case strings.Contains(lowerIdentifier, "ukrainian"):
-		return "uk"
-	case strings.Contains(lowerIdentifier, "czech"):
-		return "cs"
-	case strings.Contains(lowerIdentifier, "slovak"):
-		return "sk"
-	case strings.Contains(lowerIdentifier, "hungarian"):
-		return "hu"
-	case strings.Contains(lowerIdentifier, "romanian"):
-		return "ro"
-	case strings.Contains(lowerIdentifier, "bulgarian"):
-		return "bg"
-	case strings.Contains(lowerIdentifier, "croatian"):
-		return "hr"
-	case strings.Contains(lowerIdentifier, "serbian"):
-		return "sr"
-	case strings.Contains(lowerIdentifier, "slovenian"):
-		return "sl"
-	case strings.Contains(lowerIdentifier, "estonian"):
-		return "et"
-	case strings.Contains(lowerIdentifier, "latvian"):
-		return "lv"
-	case strings.Contains(lowerIdentifier, "lithuanian"):
-		return "lt"
-	// Add more general mappings here
-	default:
-		return "" // Return empty if no mapping found
-	}
+func init() {
+	// Keyboard layouts and input methods
+	Register(hiToolboxProvider{})
+	// System preferred languages, reported with WithKinds only
+	Register(appleLanguagesProvider{})
+	// Installed voices, reported with WithKinds only
+	Register(voiceServicesProvider{})
 }
 
-func getLanguages() ([]string, error) {
-	langSet := make(map[string]bool)
-
-	// Try to get languages from AppleEnabledInputSources (keyboard layouts)
-	cmd := exec.Command("defaults", "read", "com.apple.HIToolbox", "AppleEnabledInputSources")
-	output, err := cmd.Output()
-	if err == nil {
-		re := regexp.MustCompile(`(KeyboardLayout Name|Bundle ID) = "?([\w\.]+)"?;`)
-		matches := re.FindAllStringSubmatch(string(output), -1)
-
-		for _, match := range matches {
-			if len(match) > 2 {
-				identifier := match[2]
-				if lang := mapIdentifierToLangCode(identifier); lang != "" {
-					langSet[lang] = true
-				}
-			}
-		}
-	}
+func defaultsAvailable() bool {
+	_, err := exec.LookPath("defaults")
+	return err == nil
+}
 
-	// Try to get languages from AppleLanguages (system preferred languages)
-	fallbackLangs, err := getAppleLanguagesFallback()
-	if err == nil {
-		for _, lang := range fallbackLangs {
-			langSet[lang] = true
-		}
+// macPreferencesAvailable reports whether a preferences domain of the user
+// can be read, from its file or with defaults.
+func macPreferencesAvailable(domain string) bool {
+	if _, err := os.Stat(macPreferencesPath(domain)); err == nil {
+		return true
 	}
+	return defaultsAvailable()
+}
 
-	// Try to get languages from Voice Services (installed voices)
-	voiceLangs, err := getVoiceServicesLanguages()
-	if err == nil {
-		for _, lang := range voiceLangs {
-			langSet[lang] = true
-		}
+// readMacPreferences reads the property list of a preferences domain of the
+// user, e.g. "com.apple.HIToolbox" or ".GlobalPreferences". If the file is
+// missing, the domain is exported with "defaults export", as XML.
+func readMacPreferences(ctx context.Context, provider, domain, defaultsDomain string) ([]byte, error) {
+	data, err := os.ReadFile(macPreferencesPath(domain))
+	if err == nil || !os.IsNotExist(err) {
+		return data, err
 	}
+	return runCommand(ctx, provider, "defaults", "export", defaultsDomain, "-")
+}
 
-	langs := make([]string, 0, len(langSet))
-	for lang := range langSet {
-		langs = append(langs, lang)
-	}
+// hiToolboxProvider reads the enabled input sources from
+// AppleEnabledInputSources. It does not report the current input source:
+// only Text Input Services (TISCopyCurrentKeyboardInputSource) knows it,
+// which cannot be called without cgo, and AppleSelectedInputSources lags
+// behind a switch.
+type hiToolboxProvider struct{}
 
-	return langs, nil
+func (hiToolboxProvider) Name() string    { return "hitoolbox" }
+func (hiToolboxProvider) Available() bool {
+	return macPreferencesAvailable("com.apple.HIToolbox")
 }
 
-func getAppleLanguagesFallback() ([]string, error) {
-	cmd := exec.Command("defaults", "read", "-g", "AppleLanguages")
-	output, err := cmd.Output()
+func (hiToolboxProvider) InputSources(ctx context.Context) ([]InputSource, error) {
+	prefs, err := readHIToolboxPrefs(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return hiToolboxSources(prefs.enabled), nil
+}
 
-	langSet := make(map[string]bool)
-	// Regex to find all language codes like "en-US", "ko"
-	re := regexp.MustCompile(`"([a-zA-Z\-]+)"`)
-	matches := re.FindAllStringSubmatch(string(output), -1)
+// appleLanguagesProvider reads the system preferred languages from AppleLanguages.
+type appleLanguagesProvider struct{}
 
-	for _, match := range matches {
-		if len(match) > 1 {
-			// Normalize the extracted language tag before adding to the set
-			// This will convert "en-US" to "en", "ko-KR" to "ko"
-			langSet[normalizeLangCode(match[1])] = true
-		}
+func (appleLanguagesProvider) Name() string    { return "applelanguages" }
+func (appleLanguagesProvider) Available() bool {
+	return macPreferencesAvailable(".GlobalPreferences")
+}
+
+func (appleLanguagesProvider) InputSources(ctx context.Context) ([]InputSource, error) {
+	data, err := readMacPreferences(ctx, "applelanguages", ".GlobalPreferences", "NSGlobalDomain")
+	if err != nil {
+		return nil, err
 	}
+	prefs, err := parseGlobalPrefs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]InputSource, 0, len(prefs.languages))
+	for _, lang := range prefs.languages {
+		// Keep the full tag so that "zh-Hant" and "zh-Hans" stay distinct
+		src := newInputSource(lang, lang, lang, "applelanguages")
+		src.Kind = KindPreferredUILanguage
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
+
+// voiceServicesProvider reads the languages of the installed voices.
+type voiceServicesProvider struct{}
+
+func (voiceServicesProvider) Name() string    { return "voiceservices" }
+func (voiceServicesProvider) Available() bool { return defaultsAvailable() }
+
+func (voiceServicesProvider) InputSources(ctx context.Context) ([]InputSource, error) {
+	return getVoiceServicesLanguages(ctx)
+}
 
-	langs := make([]string, 0, len(langSet))
-	for lang := range langSet {
-		langs = append(langs, lang)
+// readHIToolboxPrefs reads the HIToolbox preferences of the user.
+func readHIToolboxPrefs(ctx context.Context) (*hiToolboxPrefs, error) {
+	data, err := readMacPreferences(ctx, "hitoolbox", "com.apple.HIToolbox", "com.apple.HIToolbox")
+	if err != nil {
+		return nil, err
 	}
+	return parseHIToolboxPrefs(data)
+}
 
-	return langs, nil
+// hiToolboxSources returns the HIToolbox input sources that have a
+// language, in order.
+func hiToolboxSources(list []hiToolboxInputSource) []InputSource {
+	var sources []InputSource
+	for _, s := range list {
+		lang, confidence := macInputSourceLanguage(s.id(), s.BundleID, s.LayoutName)
+		if lang == "" {
+			continue
+		}
+		name := s.LayoutName
+		if name == "" {
+			name = s.id()
+		}
+		src := newInputSource(s.id(), lang, name, "hitoolbox")
+		src.Confidence = confidence
+		src.Kind = s.kind()
+		sources = append(sources, src)
+	}
+	return sources
 }
 
-func getVoiceServicesLanguages() ([]string, error) {
-	cmd := exec.Command("defaults", "read", "com.apple.voiceservices")
-	output, err := cmd.Output()
+func getVoiceServicesLanguages(ctx context.Context) ([]InputSource, error) {
+	output, err := runCommand(ctx, "voiceservices", "defaults", "read", "com.apple.voiceservices")
 	if err != nil {
 		return nil, err
 	}
 
-	langSet := make(map[string]bool)
+	var sources []InputSource
 	// Regex to find Languages = ( "lang-CODE" ); blocks
 	// More flexible regex to handle varying whitespace and quotes
 	re := regexp.MustCompile(`Languages\s*=\s*\(\s*"([a-zA-Z\-]+)"\s*\);`)
@@ -194,14 +144,11 @@ func getVoiceServicesLanguages() ([]string, error) {
 
 	for _, match := range matches {
 		if len(match) > 1 {
-			langSet[normalizeLangCode(match[1])] = true
+			src := newInputSource(match[1], match[1], match[1], "voiceservices")
+			src.Kind = KindSpeechVoice
+			sources = append(sources, src)
 		}
 	}
 
-	langs := make([]string, 0, len(langSet))
-	for lang := range langSet {
-		langs = append(langs, lang)
-	}
-
-	return langs, nil
+	return sources, nil
 }
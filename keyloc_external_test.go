@@ -0,0 +1,78 @@
+package keyloc_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/lemon-mint/keyloc"
+	"github.com/lemon-mint/keyloc/keyloctest"
+)
+
+func TestGetLanguages(t *testing.T) {
+	p := keyloctest.NewProvider("fake", keyloctest.Source("us", "en"), keyloctest.Source("kr", "ko"))
+	langs, err := keyloctest.NewClient(p).Languages(context.Background())
+	if err != nil {
+		t.Fatalf("Languages() returned an error: %v", err)
+	}
+
+	expectedLangs := map[string]bool{
+		"en": true,
+		"ko": true,
+	}
+
+	foundLangs := make(map[string]bool)
+	for _, lang := range langs {
+		if expectedLangs[lang] {
+			foundLangs[lang] = true
+		}
+	}
+
+	for expectedLang := range expectedLangs {
+		if !foundLangs[expectedLang] {
+			t.Errorf("Did not find expected language: %q. Found: %v", expectedLang, langs)
+		}
+	}
+}
+
+func TestCheckLanguage(t *testing.T) {
+	p := keyloctest.NewProvider("fake", keyloctest.Source("us", "en"), keyloctest.Source("kr", "ko"))
+	client := keyloctest.NewClient(p)
+
+	tests := []struct {
+		lang     string
+		expected bool
+		name     string
+	}{
+		{"en", true, "Lowercase"},
+		{"ko", true, "Lowercase Korean"},
+		{"ru", false, "Russian not present"},
+		{"EN", true, "Uppercase"},
+		{"en-US", true, "Locale format en-US"},
+		{"en_GB", true, "Locale format en_GB"},
+		{"ko-KR", true, "Locale format ko-KR"},
+		{"ja", false, "Japanese not present"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := client.CheckLanguage(context.Background(), test.lang)
+			if err != nil {
+				t.Errorf("CheckLanguage(%q) returned an error: %v", test.lang, err)
+			}
+			if got != test.expected {
+				t.Errorf("CheckLanguage(%q) = %v, want %v", test.lang, got, test.expected)
+			}
+		})
+	}
+}
+
+func ExampleNew() {
+	p := keyloctest.NewProvider("fake", keyloctest.Source("us", "en-US"), keyloctest.Source("tw", "zh-TW"))
+	client := keyloc.New(keyloc.WithProviders(p))
+
+	hans, _ := client.CheckLanguageWithScript(context.Background(), "zh-Hans")
+	hant, _ := client.CheckLanguageWithScript(context.Background(), "zh-Hant")
+	fmt.Println(hans, hant)
+	// Output: false true
+}
@@ -3,72 +3,517 @@
 package keyloc
 
 import (
+	"context"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 )
 
-// mapLayoutToLangCode maps common X11 keyboard layouts to ISO 639-1 language codes.
-func mapLayoutToLangCode(layout string) string {
-	switch layout {
-	case "us", "gb", "ca", "au":
-		return "en"
-	case "kr":
-		return "ko"
-	case "ru":
-		return "ru"
-	case "jp":
-		return "ja"
-	case "cn":
-		return "zh"
-	case "de":
-		return "de"
-	case "fr":
-		return "fr"
-	case "es":
-		return "es"
-	// Add more mappings as needed
-	default:
-		return layout // Return the original layout if no mapping is found
-	}
-}
-
-func getLanguages() ([]string, error) {
-	// localectl often provides more reliable layout info than environment variables
-	cmd := exec.Command("localectl", "status")
-	output, err := cmd.Output()
-	if err != nil {
-		// Fallback for systems without systemd/localectl
-		cmd = exec.Command("setxkbmap", "-query")
-		output, err = cmd.Output()
-		if err != nil {
-			return nil, err
+func init() {
+	// systemd-localed often provides more reliable layout info than environment variables
+	Register(locale1Provider{})
+	// The running X server, for sessions without systemd-localed
+	Register(x11Provider{})
+	// Fallback for X servers that cannot be reached over a local socket,
+	// such as forwarded displays
+	Register(setxkbmapProvider{})
+	// The files systemd-localed and the X server read, for systems where neither is running
+	Register(xorgProvider{})
+	// Input method engines, which often sit on top of a plain "us" layout
+	Register(ibusProvider{})
+	Register(fcitx5Provider{})
+	// The user's own sources on GNOME, which may differ from the system layouts
+	Register(gnomeProvider{})
+	Register(plasmaProvider{})
+	// Wayland compositors that keep the session's layouts to themselves
+	Register(swayProvider{})
+	Register(hyprlandProvider{})
+	// Last resort for servers without a graphical session
+	Register(vconsoleProvider{})
+}
+
+// locale1Provider reads the system keyboard configuration from
+// systemd-localed's org.freedesktop.locale1 interface on the system bus,
+// which is what "localectl status" prints.
+type locale1Provider struct{}
+
+func (locale1Provider) Name() string { return "locale1" }
+
+func (locale1Provider) Available() bool {
+	for _, path := range dbusSocketPaths(systemBusAddress()) {
+		if strings.HasPrefix(path, "@") {
+			return true // Abstract sockets cannot be checked without connecting
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true
 		}
 	}
+	return false
+}
+
+func (p locale1Provider) InputSources(ctx context.Context) ([]InputSource, error) {
+	props, err := queryLocale1(ctx, systemBusAddress())
+	if err != nil {
+		return nil, err
+	}
+	return x11LayoutSources(p.Name(), "", props.layouts()), nil
+}
+
+func (p locale1Provider) CurrentInputSource(ctx context.Context) (InputSource, error) {
+	sources, err := p.InputSources(ctx)
+	if err != nil {
+		return InputSource{}, err
+	}
+	return currentX11Group(ctx, sources)
+}
+
+// Notify watches the files that systemd-localed reads its configuration from.
+func (locale1Provider) Notify(ctx context.Context) (<-chan struct{}, error) {
+	return watchFiles(ctx, watchedConfigs)
+}
+
+// x11Provider asks the X server named by $DISPLAY for its XKB
+// configuration, as "setxkbmap -query" does, without running setxkbmap.
+type x11Provider struct{}
+
+func (x11Provider) Name() string { return "x11" }
+
+func (x11Provider) Available() bool {
+	_, err := parseX11Display(os.Getenv("DISPLAY"))
+	return err == nil
+}
+
+func (p x11Provider) InputSources(ctx context.Context) ([]InputSource, error) {
+	kb, err := queryX11Keyboard(ctx, os.Getenv("DISPLAY"))
+	if err != nil {
+		return nil, err
+	}
+	return x11LayoutSources(p.Name(), "", kb.layouts()), nil
+}
+
+func (p x11Provider) CurrentInputSource(ctx context.Context) (InputSource, error) {
+	kb, err := queryX11Keyboard(ctx, os.Getenv("DISPLAY"))
+	if err != nil {
+		return InputSource{}, err
+	}
+	sources := x11LayoutSources(p.Name(), "", kb.layouts())
+	if len(sources) == 0 || kb.group < 0 {
+		return InputSource{}, ErrNoInputSource
+	}
+	if kb.group >= len(sources) {
+		return sources[0], nil
+	}
+	return sources[kb.group], nil
+}
+
+// setxkbmapProvider reads the keyboard configuration of the X session from
+// "setxkbmap -query". It is only available for displays that x11Provider
+// cannot reach itself.
+type setxkbmapProvider struct{}
+
+func (setxkbmapProvider) Name() string { return "setxkbmap" }
+
+func (setxkbmapProvider) Available() bool {
+	if os.Getenv("DISPLAY") == "" || (x11Provider{}).Available() {
+		return false
+	}
+	_, err := exec.LookPath("setxkbmap")
+	return err == nil
+}
+
+func (p setxkbmapProvider) InputSources(ctx context.Context) ([]InputSource, error) {
+	return x11CommandSources(ctx, p.Name(), "setxkbmap", "-query")
+}
+
+func (p setxkbmapProvider) CurrentInputSource(ctx context.Context) (InputSource, error) {
+	sources, err := p.InputSources(ctx)
+	if err != nil {
+		return InputSource{}, err
+	}
+	return currentX11Group(ctx, sources)
+}
+
+// xorgProvider reads the keyboard configuration of the X server from
+// xorg.conf and xorg.conf.d.
+type xorgProvider struct {
+	root string
+}
+
+func (xorgProvider) Name() string { return "xorg" }
+
+func (p xorgProvider) WithRoot(root string) Provider { return xorgProvider{root: root} }
+
+func (p xorgProvider) Available() bool {
+	for _, path := range xorgConfigFiles(p.root) {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (p xorgProvider) InputSources(ctx context.Context) ([]InputSource, error) {
+	kb, ok, err := readXorgKeyboard(xorgConfigFiles(p.root))
+	if err != nil || !ok {
+		return nil, err
+	}
+	return x11LayoutSources(p.Name(), p.root, splitX11Layouts(kb.layout, kb.variant)), nil
+}
+
+// Notify watches the X server configuration directories.
+func (p xorgProvider) Notify(ctx context.Context) (<-chan struct{}, error) {
+	dirs := map[string][]string{rootPath(p.root, filepath.Dir(xorgConfigPath)): {filepath.Base(xorgConfigPath)}}
+	for _, dir := range rootPaths(p.root, xorgConfigDirs) {
+		dirs[dir] = nil
+	}
+	return watchFiles(ctx, dirs)
+}
+
+// ibusProvider reads the input method engines the user has enabled in IBus.
+type ibusProvider struct {
+	root string
+}
+
+func (ibusProvider) Name() string { return "ibus" }
+
+func (p ibusProvider) WithRoot(root string) Provider { return ibusProvider{root: root} }
+
+// Available reports whether IBus is installed and in use: its daemon is
+// running, or the user has set its preload engines. Under a root, only the
+// setting counts.
+func (p ibusProvider) Available() bool {
+	installed := false
+	for _, dir := range rootPaths(p.root, ibusComponentDirs) {
+		if _, err := os.Stat(dir); err == nil {
+			installed = true
+			break
+		}
+	}
+	if !installed {
+		return false
+	}
+	if p.root == "" && ibusRunning() {
+		return true
+	}
+	_, ok, _ := readIBusPreloadEngines(rootPath(p.root, dconfUserDBPath()))
+	return ok
+}
+
+func (p ibusProvider) InputSources(ctx context.Context) ([]InputSource, error) {
+	var names []string
+	var err error
+	if p.root == "" {
+		names, err = ibusPreloadEngines(ctx, p.Name())
+	} else {
+		// Only the dconf database can be read without a running session
+		names, _, err = readIBusPreloadEngines(rootPath(p.root, dconfUserDBPath()))
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	engines := readIBusEngines(rootPaths(p.root, ibusComponentDirs))
+	sources := make([]InputSource, 0, len(names))
+	for _, name := range names {
+		sources = append(sources, ibusEngineSource(p.Name(), p.root, name, engines))
+	}
+	return sources, nil
+}
+
+// CurrentInputSource asks the running IBus daemon for its active engine.
+func (p ibusProvider) CurrentInputSource(ctx context.Context) (InputSource, error) {
+	if p.root != "" {
+		return InputSource{}, errNotRunning
+	}
+	output, err := runCommand(ctx, p.Name(), "ibus", "engine")
+	if err != nil {
+		return InputSource{}, err
+	}
+	name := strings.TrimSpace(string(output))
+	if name == "" {
+		return InputSource{}, ErrNoInputSource
+	}
+	return ibusEngineSource(p.Name(), "", name, readIBusEngines(ibusComponentDirs)), nil
+}
+
+// fcitx5Provider reads the input method groups of the user's Fcitx5
+// profile. The input methods of the default group come first, and its
+// default input method is the default source.
+type fcitx5Provider struct {
+	root string
+}
+
+func (fcitx5Provider) Name() string { return "fcitx5" }
+
+func (p fcitx5Provider) WithRoot(root string) Provider { return fcitx5Provider{root: root} }
+
+func (p fcitx5Provider) Available() bool {
+	_, err := os.Stat(rootPath(p.root, fcitx5ProfilePath()))
+	return err == nil
+}
+
+func (p fcitx5Provider) InputSources(ctx context.Context) ([]InputSource, error) {
+	data, err := os.ReadFile(rootPath(p.root, fcitx5ProfilePath()))
+	if err != nil {
+		return nil, err
+	}
+	profile, err := parseFcitx5Profile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ims := readFcitx5InputMethods(rootPaths(p.root, fcitx5InputMethodDirs))
+	return profile.inputSources(p.Name(), p.root, ims), nil
+}
+
+// CurrentInputSource asks the running Fcitx5 daemon for its active input method.
+func (p fcitx5Provider) CurrentInputSource(ctx context.Context) (InputSource, error) {
+	if p.root != "" {
+		return InputSource{}, errNotRunning
+	}
+	output, err := runCommand(ctx, p.Name(), "fcitx5-remote", "-n")
+	if err != nil {
+		return InputSource{}, err
+	}
+	name := strings.TrimSpace(string(output))
+	if name == "" {
+		return InputSource{}, ErrNoInputSource
+	}
+	return fcitx5Source(p.Name(), "", name, readFcitx5InputMethods(fcitx5InputMethodDirs)), nil
+}
 
-	langSet := make(map[string]bool)
-	outputStr := string(output)
-
-	// Parse localectl or setxkbmap output
-	lines := strings.Split(outputStr, "\n")
-	for _, line := range lines {
-		// For localectl: "X11 Layout: us,kr"
-		// For setxkbmap: "layout:     us,kr"
-		if strings.Contains(line, "Layout:") || strings.Contains(line, "layout:") {
-			parts := strings.Split(line, ":")
-			if len(parts) > 1 {
-				layouts := strings.Split(strings.TrimSpace(parts[1]), ",")
-				for _, layout := range layouts {
-					langSet[mapLayoutToLangCode(layout)] = true
-				}
-				break // Assume the first layout line is the most relevant
+// Notify watches the Fcitx5 profile, which Fcitx5 rewrites when the user
+// changes the input method configuration.
+func (p fcitx5Provider) Notify(ctx context.Context) (<-chan struct{}, error) {
+	return watchFile(ctx, rootPath(p.root, fcitx5ProfilePath()))
+}
+
+// gnomeProvider reads the GNOME input sources (org.gnome.desktop.input-sources)
+// directly from the user's dconf database.
+type gnomeProvider struct {
+	root string
+}
+
+func (gnomeProvider) Name() string { return "gnome" }
+
+func (p gnomeProvider) WithRoot(root string) Provider { return gnomeProvider{root: root} }
+
+func (p gnomeProvider) Available() bool {
+	_, err := os.Stat(rootPath(p.root, dconfUserDBPath()))
+	return err == nil
+}
+
+func (p gnomeProvider) InputSources(ctx context.Context) ([]InputSource, error) {
+	entries, err := readGnomeSources(rootPath(p.root, dconfUserDBPath()), gnomeSourcesKey)
+	if err != nil {
+		return nil, err
+	}
+	return gnomeInputSources(p.Name(), p.root, entries, rootPaths(p.root, ibusComponentDirs)), nil
+}
+
+// CurrentInputSource returns the most recently used input source, which
+// GNOME keeps at the front of mru-sources.
+func (p gnomeProvider) CurrentInputSource(ctx context.Context) (InputSource, error) {
+	path := rootPath(p.root, dconfUserDBPath())
+	entries, err := readGnomeSources(path, gnomeSourcesKey)
+	if err != nil {
+		return InputSource{}, err
+	}
+	if len(entries) == 0 {
+		return InputSource{}, ErrNoInputSource
+	}
+
+	current := entries[0]
+	if mru, err := readGnomeSources(path, gnomeMRUSourcesKey); err == nil {
+		for _, e := range mru {
+			if slices.Contains(entries, e) {
+				current = e
+				break
 			}
 		}
 	}
+	return gnomeInputSources(p.Name(), p.root, []gnomeSource{current}, rootPaths(p.root, ibusComponentDirs))[0], nil
+}
+
+// Notify watches the dconf database, which dconf replaces on every change.
+func (p gnomeProvider) Notify(ctx context.Context) (<-chan struct{}, error) {
+	return watchFile(ctx, rootPath(p.root, dconfUserDBPath()))
+}
+
+// plasmaProvider reads the keyboard layouts configured in KDE Plasma.
+type plasmaProvider struct {
+	root string
+}
+
+func (plasmaProvider) Name() string { return "plasma" }
+
+func (p plasmaProvider) WithRoot(root string) Provider { return plasmaProvider{root: root} }
+
+func (p plasmaProvider) Available() bool {
+	_, err := os.Stat(rootPath(p.root, kxkbrcPath()))
+	return err == nil
+}
 
-	langs := make([]string, 0, len(langSet))
-	for lang := range langSet {
-		langs = append(langs, lang)
+func (p plasmaProvider) InputSources(ctx context.Context) ([]InputSource, error) {
+	data, err := os.ReadFile(rootPath(p.root, kxkbrcPath()))
+	if err != nil {
+		return nil, err
 	}
+	return parseKxkbrc(data).inputSources(p.Name(), p.root), nil
+}
+
+// Notify watches kxkbrc, which Plasma rewrites when the layouts change.
+func (p plasmaProvider) Notify(ctx context.Context) (<-chan struct{}, error) {
+	return watchFile(ctx, rootPath(p.root, kxkbrcPath()))
+}
+
+// swayProvider asks the running sway compositor for its keyboard layouts.
+type swayProvider struct{}
+
+func (swayProvider) Name() string { return "sway" }
 
-	return langs, nil
+func (swayProvider) Available() bool { return swaySocketPath() != "" }
+
+func (p swayProvider) InputSources(ctx context.Context) ([]InputSource, error) {
+	sources, _, err := swayInputSources(ctx, p.Name(), swaySocketPath())
+	return sources, err
+}
+
+func (p swayProvider) CurrentInputSource(ctx context.Context) (InputSource, error) {
+	sources, active, err := swayInputSources(ctx, p.Name(), swaySocketPath())
+	if err != nil {
+		return InputSource{}, err
+	}
+	if len(sources) == 0 {
+		return InputSource{}, ErrNoInputSource
+	}
+	return sources[active], nil
+}
+
+// hyprlandProvider asks the running Hyprland compositor for its keyboard layouts.
+type hyprlandProvider struct{}
+
+func (hyprlandProvider) Name() string { return "hyprland" }
+
+func (hyprlandProvider) Available() bool { return hyprlandSocketPath() != "" }
+
+func (p hyprlandProvider) InputSources(ctx context.Context) ([]InputSource, error) {
+	sources, _, err := hyprlandInputSources(ctx, p.Name(), hyprlandSocketPath())
+	return sources, err
+}
+
+func (p hyprlandProvider) CurrentInputSource(ctx context.Context) (InputSource, error) {
+	sources, active, err := hyprlandInputSources(ctx, p.Name(), hyprlandSocketPath())
+	if err != nil {
+		return InputSource{}, err
+	}
+	if len(sources) == 0 {
+		return InputSource{}, ErrNoInputSource
+	}
+	return sources[active], nil
+}
+
+// vconsoleProvider reads the virtual console keymap and the system XKB
+// layouts from their configuration files, for systems without a graphical
+// session or systemd-localed.
+type vconsoleProvider struct {
+	root string
+}
+
+func (vconsoleProvider) Name() string { return "vconsole" }
+
+func (p vconsoleProvider) WithRoot(root string) Provider { return vconsoleProvider{root: root} }
+
+func (p vconsoleProvider) Available() bool {
+	for _, path := range []string{vconsoleConfPath, debianKeyboardPath} {
+		if _, err := os.Stat(rootPath(p.root, path)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (p vconsoleProvider) InputSources(ctx context.Context) ([]InputSource, error) {
+	vconsole, err := os.ReadFile(rootPath(p.root, vconsoleConfPath))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	keyboard, err := os.ReadFile(rootPath(p.root, debianKeyboardPath))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return vconsoleSources(p.Name(), p.root, vconsole, keyboard), nil
+}
+
+// Notify watches the console and system keyboard configuration files.
+func (p vconsoleProvider) Notify(ctx context.Context) (<-chan struct{}, error) {
+	vconsole, keyboard := rootPath(p.root, vconsoleConfPath), rootPath(p.root, debianKeyboardPath)
+	return watchFiles(ctx, map[string][]string{
+		filepath.Dir(vconsole): {filepath.Base(vconsole)},
+		filepath.Dir(keyboard): {filepath.Base(keyboard)},
+	})
+}
+
+// x11CommandSources runs a command that prints an X11 layout list and
+// returns the layouts as input sources.
+func x11CommandSources(ctx context.Context, backend, name string, args ...string) ([]InputSource, error) {
+	output, err := runCommand(ctx, backend, name, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	layouts, err := parseX11Layouts(string(output))
+	if err != nil {
+		return nil, err
+	}
+	return x11LayoutSources(backend, "", layouts), nil
+}
+
+// parseX11Layouts extracts the layouts and their variants from
+// "localectl status" or "setxkbmap -query" output.
+func parseX11Layouts(output string) ([]x11Layout, error) {
+	var layouts, variants string
+	found := false
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// localectl prints "X11 Layout: us,kr", setxkbmap "layout:     us,kr"
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "x11 layout", "layout":
+			if !found {
+				layouts, found = value, true
+			}
+		case "x11 variant", "variant":
+			variants = value
+		}
+	}
+	if !found {
+		return nil, parseError("no keyboard layout in %q", output)
+	}
+	return splitX11Layouts(layouts, variants), nil
+}
+
+// currentX11Group returns the input source selected by the active XKB
+// group of the X server, which indexes the configured layouts in the order
+// they were listed. Without an X server that supports XKB, the active
+// source is unknown.
+func currentX11Group(ctx context.Context, sources []InputSource) (InputSource, error) {
+	kb, err := queryX11Keyboard(ctx, os.Getenv("DISPLAY"))
+	if ctx.Err() != nil {
+		return InputSource{}, ctx.Err()
+	}
+	if err != nil || kb.group < 0 || len(sources) == 0 {
+		return InputSource{}, ErrNoInputSource
+	}
+	if kb.group >= len(sources) {
+		return sources[0], nil
+	}
+	return sources[kb.group], nil
 }
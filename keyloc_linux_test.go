@@ -0,0 +1,282 @@
+//go:build linux
+
+package keyloc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseX11Layouts(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected []x11Layout
+	}{
+		{"   System Locale: LANG=en_US.UTF-8\n       VC Keymap: us\n      X11 Layout: us,kr\n", []x11Layout{{"us", ""}, {"kr", ""}}},
+		{"rules:      evdev\nmodel:      pc105\nlayout:     de, ru\nvariant:    nodeadkeys,phonetic\n", []x11Layout{{"de", "nodeadkeys"}, {"ru", "phonetic"}}},
+		{"      X11 Layout: us,ara\n     X11 Variant: ,qwerty\n", []x11Layout{{"us", ""}, {"ara", "qwerty"}}},
+		{"   System Locale: LANG=C.UTF-8\n       VC Keymap: n/a\n      X11 Layout: n/a\n", nil},
+	}
+
+	for _, test := range tests {
+		got, err := parseX11Layouts(test.output)
+		if err != nil {
+			t.Errorf("parseX11Layouts(%q) returned an error: %v", test.output, err)
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("parseX11Layouts(%q) = %v, want %v", test.output, got, test.expected)
+		}
+	}
+
+	if _, err := parseX11Layouts("rules:      evdev\n"); !errors.Is(err, ErrParse) {
+		t.Errorf("parseX11Layouts() without a layout error = %v, want %v", err, ErrParse)
+	}
+}
+
+func TestWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notify, err := watchFiles(ctx, map[string][]string{dir: {"vconsole.conf"}})
+	if err != nil {
+		t.Fatalf("watchFiles() returned an error: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "unrelated"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vconsole.conf"), []byte("KEYMAP=de\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-notify:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification after writing a watched file")
+	}
+
+	cancel()
+	for range notify {
+	}
+}
+
+func TestWithRootFixtureTree(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", "/home/test/.config")
+	files := map[string]string{
+		"etc/vconsole.conf":                      "KEYMAP=de-latin1-nodeadkeys\n",
+		"etc/default/keyboard":                   "XKBLAYOUT=\"de,us\"\nXKBVARIANT=\"nodeadkeys,\"\n",
+		"home/test/.config/kxkbrc":               "[Layout]\nLayoutList=us,ru\nUse=true\n",
+		"home/test/.config/fcitx5/profile":       "[Groups/0]\nName=Default\n\n[Groups/0/Items/0]\nName=keyboard-us\n\n[Groups/0/Items/1]\nName=mozc\n\n[GroupOrder]\n0=Default\n",
+		"usr/share/fcitx5/inputmethod/mozc.conf": "[InputMethod]\nName=Mozc\nLangCode=ja\n",
+		"etc/X11/xorg.conf.d/00-keyboard.conf":   "Section \"InputClass\"\n\tIdentifier \"system-keyboard\"\n\tMatchIsKeyboard \"on\"\n\tOption \"XkbLayout\" \"fr\"\nEndSection\n",
+		// The XKB rules and kbd-model-map of the image, rather than those
+		// of the host, describe its layouts
+		"usr/share/X11/xkb/rules/evdev.xml": strings.Replace(testEvdevXML, "</layoutList>",
+			"<layout><configItem><name>fr</name><description>Breton</description><countryList><iso3166Id>FR</iso3166Id></countryList><languageList><iso639Id>bre</iso639Id></languageList></configItem></layout></layoutList>", 1),
+		"usr/share/systemd/kbd-model-map": "de-latin1-nodeadkeys us pc105 intl\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := New(WithRoot(root)).Query(context.Background())
+	if err != nil {
+		t.Fatalf("Query() returned an error: %v", err)
+	}
+	if expected := []string{"xorg", "fcitx5", "plasma", "vconsole"}; !reflect.DeepEqual(r.Succeeded, expected) {
+		t.Errorf("Query() succeeded = %v, want %v (failed: %v)", r.Succeeded, expected, r.Err())
+	}
+
+	var got []string
+	for _, src := range r.Sources {
+		got = append(got, src.Backend+":"+src.ID+":"+src.Language)
+	}
+	expected := []string{
+		"xorg:fr:br-FR",
+		"fcitx5:keyboard-us:en-US",
+		"fcitx5:mozc:ja",
+		"plasma:us:en-US",
+		"plasma:ru:ru",
+		"vconsole:de-latin1-nodeadkeys:en-US",
+		"vconsole:de(nodeadkeys):de",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Query() sources = %v, want %v", got, expected)
+	}
+
+	// The rules of the image are cached apart from those of the host
+	if src := x11LayoutSource("test", "", x11Layout{layout: "fr"}); src.Language != "fr-FR" {
+		t.Errorf("x11LayoutSource() of the host = %+v, want fr-FR", src)
+	}
+}
+
+func TestIBusProviderAvailable(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", "/home/test/.config")
+	t.Setenv("IBUS_ADDRESS", "")
+	for _, dir := range []string{"usr/share/ibus/component", "home/test/.config/ibus/bus"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "home/test/.config/ibus/bus/machine-unix-0"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bus address of an image does not mean that IBus is running
+	p := ibusProvider{root: root}
+	if p.Available() {
+		t.Error("Available() without preload engines = true")
+	}
+	dconf := buildGVDB(binary.LittleEndian, map[string]string{ibusPreloadEnginesKey: "hangul\x00\x07\x00as"})
+	if err := os.MkdirAll(filepath.Join(root, "home/test/.config/dconf"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "home/test/.config/dconf/user"), dconf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !p.Available() {
+		t.Error("Available() with preload engines = false")
+	}
+	sources, err := p.InputSources(context.Background())
+	if err != nil || len(sources) != 1 || sources[0].ID != "hangul" {
+		t.Errorf("InputSources() = %+v, %v, want hangul", sources, err)
+	}
+
+	// On the host, a running daemon is enough
+	old := ibusComponentDirs
+	ibusComponentDirs = []string{filepath.Join(root, "usr/share/ibus/component")}
+	t.Cleanup(func() { ibusComponentDirs = old })
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	if (ibusProvider{}).Available() {
+		t.Error("Available() without a running daemon = true")
+	}
+	t.Setenv("IBUS_ADDRESS", "unix:path=/tmp/ibus-test")
+	if !(ibusProvider{}).Available() {
+		t.Error("Available() with IBUS_ADDRESS = false")
+	}
+	t.Setenv("IBUS_ADDRESS", "")
+	if err := os.MkdirAll(filepath.Join(config, "ibus/bus"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(config, "ibus/bus/machine-unix-0"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !(ibusProvider{}).Available() {
+		t.Error("Available() with a bus address file = false")
+	}
+}
+
+func TestFcitx5Provider(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", "/home/test/.config")
+	files := map[string]string{
+		"home/test/.config/fcitx5/profile":         testFcitx5Profile,
+		"usr/share/fcitx5/inputmethod/pinyin.conf": "[InputMethod]\nName=Pinyin\nLangCode=zh_CN\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sources, err := fcitx5Provider{root: root}.InputSources(context.Background())
+	if err != nil {
+		t.Fatalf("InputSources() returned an error: %v", err)
+	}
+	type source struct {
+		id, name  string
+		isDefault bool
+	}
+	var got []source
+	for _, src := range sources {
+		got = append(got, source{src.ID, src.Name, src.Default})
+	}
+	expected := []source{
+		{"keyboard-us", "English (US) [Default]", false},
+		{"mozc", "mozc [Default]", false},
+		{"pinyin", "Pinyin [Default]", true},
+		{"keyboard-de-nodeadkeys", "German (no dead keys) [Other]", false},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("InputSources() = %v, want %v", got, expected)
+	}
+
+	// Without a DefaultIM the first input method of the default group is the default
+	profile := "[Groups/0]\nName=Default\n\n[Groups/0/Items/0]\nName=keyboard-us\n\n[Groups/0/Items/1]\nName=pinyin\n"
+	if err := os.WriteFile(filepath.Join(root, "home/test/.config/fcitx5/profile"), []byte(profile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sources, err = fcitx5Provider{root: root}.InputSources(context.Background())
+	if err != nil {
+		t.Fatalf("InputSources() returned an error: %v", err)
+	}
+	if len(sources) != 2 || !sources[0].Default || sources[1].Default {
+		t.Errorf("InputSources() without a DefaultIM = %+v, want keyboard-us as the default", sources)
+	}
+}
+
+func TestX11Provider(t *testing.T) {
+	t.Setenv("XAUTHORITY", filepath.Join(t.TempDir(), "missing"))
+	server := fakeX11{rules: "evdev\x00pc105\x00us,de\x00,nodeadkeys\x00\x00", xkb: true, group: 1}
+	t.Setenv("DISPLAY", serveUnix(t, server.serve)+":0")
+
+	p := x11Provider{}
+	if !p.Available() {
+		t.Fatalf("Available() = false with DISPLAY=%s", os.Getenv("DISPLAY"))
+	}
+	sources, err := p.InputSources(context.Background())
+	if err != nil {
+		t.Fatalf("InputSources() returned an error: %v", err)
+	}
+	if len(sources) != 2 || sources[0].ID != "us" || sources[1].ID != "de(nodeadkeys)" {
+		t.Errorf("InputSources() = %+v", sources)
+	}
+	src, err := p.CurrentInputSource(context.Background())
+	if err != nil {
+		t.Fatalf("CurrentInputSource() returned an error: %v", err)
+	}
+	if src.ID != "de(nodeadkeys)" || src.Language != "de-DE" || src.Backend != "x11" {
+		t.Errorf("CurrentInputSource() = %+v, want de(nodeadkeys)", src)
+	}
+
+	// setxkbmap stands in only for displays that x11 cannot reach
+	if (setxkbmapProvider{}).Available() {
+		t.Errorf("setxkbmap Available() = true for a local display")
+	}
+
+	// Without XKB the active group is unknown
+	server.xkb = false
+	t.Setenv("DISPLAY", serveUnix(t, server.serve)+":0")
+	if _, err := p.CurrentInputSource(context.Background()); !errors.Is(err, ErrNoInputSource) {
+		t.Errorf("CurrentInputSource() without XKB error = %v, want %v", err, ErrNoInputSource)
+	}
+	if _, err := currentX11Group(context.Background(), sources); !errors.Is(err, ErrNoInputSource) {
+		t.Errorf("currentX11Group() without XKB error = %v, want %v", err, ErrNoInputSource)
+	}
+
+	t.Setenv("DISPLAY", "remote:0")
+	if p.Available() {
+		t.Errorf("Available() = true for a remote display")
+	}
+}
@@ -1,97 +1,76 @@
 package keyloc
 
 import (
+	"context"
+	"errors"
 	"testing"
-	"runtime"
 )
 
-func TestGetLanguages(t *testing.T) {
-	langs, err := getLanguages()
-	if err != nil {
-		t.Fatalf("getLanguages() returned an error: %v", err)
-	}
-
-	expectedLangs := map[string]bool{
-		"en": true,
-	}
-
-	// Only expect 'ko' on macOS
-	if runtime.GOOS == "darwin" {
-		expectedLangs["ko"] = true
-	}
-
-	foundLangs := make(map[string]bool)
-	for _, lang := range langs {
-		normalized := normalizeLangCode(lang)
-		if expectedLangs[normalized] {
-			foundLangs[normalized] = true
-		}
+func TestNormalizeLangCode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"en-US", "en"},
+		{"fr_CA", "fr"},
+		{"ZH-Hant", "zh"},
+		{"es", "es"},
+		{"DE", "de"},
+		{"iw", "he"},
+		{"deu-DE", "de"},
 	}
 
-	for expectedLang := range expectedLangs {
-		if !foundLangs[expectedLang] {
-			t.Errorf("Did not find expected language: %q. Found: %v", expectedLang, langs)
+	for _, test := range tests {
+		if normalizeLangCode(test.input) != test.expected {
+			t.Errorf("normalizeLangCode(%q) = %q, want %q", test.input, normalizeLangCode(test.input), test.expected)
 		}
 	}
 }
 
-func TestCheckLanguage(t *testing.T) {
+func TestNewInputSource(t *testing.T) {
 	tests := []struct {
-		lang     string
-		expected bool
-		name     string
-		skipOnOS []string // list of OS to skip on
+		lang   string
+		script string
+		region string
 	}{
-		{"en", true, "Lowercase", nil},
-		{"ko", true, "Lowercase Korean", []string{"linux", "windows"}},
-		{"ru", false, "Russian not present", nil},
-		{"EN", true, "Uppercase", nil},
-		{"en-US", true, "Locale format en-US", nil},
-		{"en_GB", true, "Locale format en_GB", nil},
-		{"ko-KR", true, "Locale format ko-KR", []string{"linux", "windows"}},
-		{"ja", false, "Japanese not present", nil},
+		{"en", "", ""},
+		{"zh-CN", "", "CN"},
+		{"zh-hant-TW", "Hant", "TW"},
+		{"sr_Latn", "Latn", ""},
+		{"es-419", "", "419"},
 	}
 
 	for _, test := range tests {
-		shouldSkip := false
-		for _, skipOS := range test.skipOnOS {
-			if skipOS == runtime.GOOS {
-				shouldSkip = true
-				break
-			}
+		src := newInputSource("id", test.lang, "name", "test")
+		if src.Script != test.script || src.Region != test.region {
+			t.Errorf("newInputSource(%q) script, region = %q, %q, want %q, %q", test.lang, src.Script, src.Region, test.script, test.region)
 		}
+	}
+}
 
-		if shouldSkip {
-			t.Logf("Skipping test %q on %s", test.name, runtime.GOOS)
-			continue
-		}
-		t.Run(test.name, func(t *testing.T) {
-			got, err := CheckLanguage(test.lang)
-			if err != nil {
-				t.Errorf("CheckLanguage(%q) returned an error: %v", test.lang, err)
-			}
-			if got != test.expected {
-				t.Errorf("CheckLanguage(%q) = %v, want %v", test.lang, got, test.expected)
-			}
-		})
+func TestRunCommand(t *testing.T) {
+	_, err := runCommand(context.Background(), "test", "keyloc-command-that-does-not-exist")
+	var pe *ProviderError
+	if !errors.As(err, &pe) || pe.Provider != "test" {
+		t.Fatalf("runCommand() error = %v, want a *ProviderError for test", err)
+	}
+	if !errors.Is(err, ErrCommandNotFound) {
+		t.Errorf("runCommand() error = %v, want %v", err, ErrCommandNotFound)
 	}
 }
 
-func TestNormalizeLangCode(t *testing.T) {
+func TestProviderError(t *testing.T) {
 	tests := []struct {
-		input    string
+		err      *ProviderError
 		expected string
 	}{
-		{"en-US", "en"},
-		{"fr_CA", "fr"},
-		{"ZH-Hant", "zh"},
-		{"es", "es"},
-		{"DE", "de"},
+		{&ProviderError{Provider: "ibus", Stderr: "no bus", Err: ErrCommandNotFound}, "keyloc: provider ibus: " + ErrCommandNotFound.Error() + ": no bus"},
+		{&ProviderError{Provider: "ibus", Stderr: "no bus"}, "keyloc: provider ibus: no bus"},
+		{&ProviderError{Provider: "ibus"}, "keyloc: provider ibus"},
 	}
-
-	for _, test := range tests {
-		if normalizeLangCode(test.input) != test.expected {
-			t.Errorf("normalizeLangCode(%q) = %q, want %q", test.input, normalizeLangCode(test.input), test.expected)
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.expected {
+			t.Errorf("%#v.Error() = %q, want %q", tt.err, got, tt.expected)
 		}
 	}
 }
@@ -0,0 +1,270 @@
+// Package keyloctest provides a fake keyloc.Provider for hermetic tests of
+// code built on keyloc.
+//
+// A Provider starts out with a fixed list of input sources. Tests can then
+// script changes (Add, Remove, Switch, SetSources) that are delivered to
+// clients watching the provider, and inject errors with SetError.
+package keyloctest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lemon-mint/keyloc"
+)
+
+// Provider is a configurable, in-memory keyloc.Provider. It also
+// implements keyloc.CurrentProvider and keyloc.NotifyProvider. All methods
+// are safe for concurrent use.
+type Provider struct {
+	mu          sync.Mutex
+	name        string
+	sources     []keyloc.InputSource
+	current     string
+	err         error
+	delay       time.Duration
+	unavailable bool
+	calls       int
+	subscribers []chan struct{}
+}
+
+// NewProvider returns a fake provider with the given input sources. The
+// first source is the active one.
+func NewProvider(name string, sources ...keyloc.InputSource) *Provider {
+	p := &Provider{name: name}
+	p.sources = p.withBackend(sources)
+	if len(sources) > 0 {
+		p.current = sources[0].ID
+	}
+	return p
+}
+
+// NewClient returns a keyloc client that queries only the given providers
+// and polls them every millisecond when watching.
+func NewClient(providers ...keyloc.Provider) *keyloc.Client {
+	return keyloc.New(keyloc.WithProviders(providers...), keyloc.WithPollInterval(time.Millisecond))
+}
+
+// Source returns an input source with the given ID and language tag, with
+// the script and region filled in from the tag.
+func Source(id, lang string) keyloc.InputSource {
+	src := keyloc.InputSource{ID: id, Language: lang, Name: id}
+	if t, err := keyloc.ParseTag(lang); err == nil {
+		src.Language = t.String()
+		src.Script = t.Script
+		src.Region = t.Region
+	}
+	return src
+}
+
+// Name implements keyloc.Provider.
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// Available implements keyloc.Provider.
+func (p *Provider) Available() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return !p.unavailable
+}
+
+// InputSources implements keyloc.Provider. It returns the error set with
+// SetError, if any.
+func (p *Provider) InputSources(ctx context.Context) ([]keyloc.InputSource, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return append([]keyloc.InputSource(nil), p.sources...), nil
+}
+
+// CurrentInputSource implements keyloc.CurrentProvider. It returns the
+// error set with SetError, if any.
+func (p *Provider) CurrentInputSource(ctx context.Context) (keyloc.InputSource, error) {
+	if err := p.wait(ctx); err != nil {
+		return keyloc.InputSource{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return keyloc.InputSource{}, p.err
+	}
+	for _, src := range p.sources {
+		if src.ID == p.current {
+			return src, nil
+		}
+	}
+	return keyloc.InputSource{}, keyloc.ErrNoInputSource
+}
+
+// Notify implements keyloc.NotifyProvider. The returned channel receives
+// a value after every scripted change.
+func (p *Provider) Notify(ctx context.Context) (<-chan struct{}, error) {
+	ch := make(chan struct{}, 1)
+
+	p.mu.Lock()
+	p.subscribers = append(p.subscribers, ch)
+	p.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for i, sub := range p.subscribers {
+			if sub == ch {
+				p.subscribers = append(p.subscribers[:i], p.subscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// Calls returns how many times InputSources has been called.
+func (p *Provider) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.calls
+}
+
+// SetSources replaces the input sources of the provider.
+func (p *Provider) SetSources(sources ...keyloc.InputSource) {
+	p.mu.Lock()
+	p.sources = p.withBackend(sources)
+	p.mu.Unlock()
+	p.notify()
+}
+
+// Add adds an input source after the existing ones.
+func (p *Provider) Add(src keyloc.InputSource) {
+	p.mu.Lock()
+	p.sources = append(p.sources, p.withBackend([]keyloc.InputSource{src})...)
+	p.mu.Unlock()
+	p.notify()
+}
+
+// Remove removes the input source with the given ID. It reports whether
+// such a source existed.
+func (p *Provider) Remove(id string) bool {
+	p.mu.Lock()
+	removed := false
+	for i, src := range p.sources {
+		if src.ID == id {
+			p.sources = append(p.sources[:i:i], p.sources[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	p.mu.Unlock()
+	if removed {
+		p.notify()
+	}
+	return removed
+}
+
+// Switch makes the input source with the given ID the active one.
+func (p *Provider) Switch(id string) error {
+	p.mu.Lock()
+	found := false
+	for _, src := range p.sources {
+		if src.ID == id {
+			found = true
+			break
+		}
+	}
+	if found {
+		p.current = id
+	}
+	p.mu.Unlock()
+
+	if !found {
+		return fmt.Errorf("keyloctest: provider %q has no input source %q", p.name, id)
+	}
+	p.notify()
+	return nil
+}
+
+// SetError makes every following query fail with err, or succeed again if err is nil.
+func (p *Provider) SetError(err error) {
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+	p.notify()
+}
+
+// SetDelay makes every following query take d, or return early with the
+// context error if its context is done first. It simulates a slow backend.
+func (p *Provider) SetDelay(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.delay = d
+}
+
+// SetAvailable sets whether the provider reports itself as available.
+func (p *Provider) SetAvailable(available bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.unavailable = !available
+}
+
+// wait sleeps for the configured delay, or until ctx is done.
+func (p *Provider) wait(ctx context.Context) error {
+	p.mu.Lock()
+	delay := p.delay
+	p.mu.Unlock()
+
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withBackend returns a copy of sources whose Backend defaults to the provider name.
+func (p *Provider) withBackend(sources []keyloc.InputSource) []keyloc.InputSource {
+	out := make([]keyloc.InputSource, len(sources))
+	for i, src := range sources {
+		if src.Backend == "" {
+			src.Backend = p.name
+		}
+		out[i] = src
+	}
+	return out
+}
+
+// notify signals every watcher without blocking.
+func (p *Provider) notify() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, ch := range p.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package keyloctest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lemon-mint/keyloc"
+)
+
+func TestProvider(t *testing.T) {
+	p := NewProvider("fake", Source("us", "en-US"), Source("kr", "ko-KR"))
+	c := NewClient(p)
+
+	langs, err := c.Languages(context.Background())
+	if err != nil {
+		t.Fatalf("Languages() returned an error: %v", err)
+	}
+	if len(langs) != 2 || langs[0] != "en-US" || langs[1] != "ko-KR" {
+		t.Errorf("Languages() = %v, want [en-US ko-KR]", langs)
+	}
+
+	current, err := c.CurrentInputSource(context.Background())
+	if err != nil || current.ID != "us" || current.Backend != "fake" {
+		t.Errorf("CurrentInputSource() = %+v, %v, want us from fake", current, err)
+	}
+
+	boom := errors.New("boom")
+	p.SetError(boom)
+	if _, err := c.CheckLanguage(context.Background(), "en"); !errors.Is(err, boom) {
+		t.Errorf("CheckLanguage() error = %v, want %v", err, boom)
+	}
+	p.SetError(nil)
+
+	p.SetAvailable(false)
+	if _, err := c.InputSources(context.Background()); err == nil {
+		t.Error("InputSources() with an unavailable provider returned no error")
+	}
+}
+
+func TestProviderTimeout(t *testing.T) {
+	slow := NewProvider("slow", Source("jp", "ja"))
+	slow.SetDelay(time.Hour)
+	fast := NewProvider("fast", Source("us", "en"))
+	c := keyloc.New(keyloc.WithProviders(slow, fast), keyloc.WithProviderTimeout(50*time.Millisecond))
+
+	sources, err := c.InputSources(context.Background())
+	if err != nil {
+		t.Fatalf("InputSources() returned an error: %v", err)
+	}
+	if len(sources) != 1 || sources[0].ID != "us" {
+		t.Errorf("InputSources() = %+v, want only the fast provider's source", sources)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.InputSources(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("InputSources() with a canceled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	p := NewProvider("fake", Source("us", "en-US"), Source("kr", "ko-KR"))
+	c := NewClient(p)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := c.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch() returned an error: %v", err)
+	}
+
+	next := func() keyloc.Event {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an event")
+			return keyloc.Event{}
+		}
+	}
+
+	p.Add(Source("ru", "ru"))
+	if ev := next(); ev.Type != keyloc.SourceAdded || ev.Source.ID != "ru" {
+		t.Errorf("event after Add = %v %+v, want SourceAdded ru", ev.Type, ev.Source)
+	}
+
+	if err := p.Switch("kr"); err != nil {
+		t.Fatalf("Switch() returned an error: %v", err)
+	}
+	if ev := next(); ev.Type != keyloc.CurrentChanged || ev.Source.ID != "kr" || ev.Previous.ID != "us" {
+		t.Errorf("event after Switch = %v %+v, want CurrentChanged from us to kr", ev.Type, ev)
+	}
+
+	// A failing provider keeps its last known input sources
+	calls := p.Calls()
+	p.SetError(errors.New("boom"))
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Calls() == calls {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the watcher to query the failing provider")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("event while the provider fails = %v %+v, want none", ev.Type, ev.Source)
+	case <-time.After(50 * time.Millisecond):
+	}
+	p.SetError(nil)
+	sources, err := c.InputSources(ctx)
+	if err != nil {
+		t.Fatalf("InputSources() after the error was cleared returned an error: %v", err)
+	}
+	var ids []string
+	for _, src := range sources {
+		ids = append(ids, src.ID)
+	}
+	if expected := []string{"us", "kr", "ru"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("InputSources() after the error was cleared = %v, want %v", ids, expected)
+	}
+
+	p.Remove("us")
+	if ev := next(); ev.Type != keyloc.SourceRemoved || ev.Source.ID != "us" {
+		t.Errorf("event after Remove = %v %+v, want SourceRemoved us", ev.Type, ev.Source)
+	}
+
+	cancel()
+	for range events {
+	}
+}
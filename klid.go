@@ -0,0 +1,105 @@
+package keyloc
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// klid is a Windows keyboard layout identifier, written as eight hex
+// digits in the registry, e.g. "00010409" for US Dvorak. The low word is
+// the language ID of the layout and the high word tells the layouts of a
+// language apart. IMEs have KLIDs of the form "E0xxxxxx", and the Preload
+// key may refer to a layout by a substitute such as "d0010407", which the
+// Substitutes key maps to the KLID of the layout.
+type klid uint32
+
+// parseKLID parses a KLID as written in the registry.
+func parseKLID(s string) (klid, error) {
+	if len(s) != 8 {
+		return 0, parseError("bad KLID %q", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, parseError("bad KLID %q", s)
+	}
+	return klid(v), nil
+}
+
+func (k klid) String() string { return fmt.Sprintf("%08X", uint32(k)) }
+
+// langID returns the language ID in the low word of the KLID.
+func (k klid) langID() uint16 { return uint16(k) }
+
+// ime reports whether the KLID names an IME rather than a keyboard layout.
+func (k klid) ime() bool { return k>>28 == 0xe }
+
+// windowsLayout holds the values of a layout under
+// HKLM\SYSTEM\CurrentControlSet\Control\Keyboard Layouts\<KLID>.
+type windowsLayout struct {
+	text string // Layout Text, e.g. "United States-Dvorak"
+	id   string // Layout Id, e.g. "0002", for all but the first layout of a language
+}
+
+// hkl returns the keyboard layout handle that Windows loads for the layout
+// when it is used for input language lang, as GetKeyboardLayoutList would
+// report it: the language in the low word, and in the high word the
+// language of the layout or, for additional layouts, 0xF000 with their
+// layout ID. IMEs keep their KLID. It reports false if the layout ID of an
+// additional layout is unknown.
+func (k klid) hkl(lang uint16, layout windowsLayout) (HKL, bool) {
+	switch {
+	case k.ime():
+		return decodeHKL(uint32(k)), true
+	case k>>16 == 0:
+		return decodeHKL(uint32(k.langID())<<16 | uint32(lang)), true
+	}
+	id, err := strconv.ParseUint(layout.id, 16, 16)
+	if err != nil || id > 0x0fff {
+		return HKL{}, false
+	}
+	return decodeHKL((0xf000|uint32(id))<<16 | uint32(lang)), true
+}
+
+// preloadSources returns the keyboard layouts of the Preload key, whose
+// values "1", "2", … are given in order, as input sources. The language of
+// each source is that of the Preload entry, and its layout the entry or its
+// substitute. Sources are identified by HKL like those of user32, or by
+// KLID when the HKL cannot be told. substitute looks up a value of the
+// Substitutes key, and layout the registry key of a KLID.
+func preloadSources(backend string, preload []string, substitute func(string) (string, bool), layout func(klid) windowsLayout) ([]InputSource, error) {
+	sources := make([]InputSource, 0, len(preload))
+	for i, entry := range preload {
+		input, err := parseKLID(entry)
+		if err != nil {
+			return nil, err
+		}
+		k := input
+		if sub, ok := substitute(entry); ok {
+			if k, err = parseKLID(sub); err != nil {
+				return nil, err
+			}
+		}
+
+		l := layout(k)
+		id := k.String()
+		hkl, ok := k.hkl(input.langID(), l)
+		if ok {
+			id = hkl.String()
+		}
+		name := l.text
+		if name == "" {
+			name = k.String()
+		}
+		src := newInputSource(id, langIDTag(input.langID()), name, backend)
+		if ok {
+			src.HKL = hkl
+		}
+		if k.ime() {
+			src.Kind = KindInputMethod
+		}
+		// The first entry is the default input language
+		src.Default = i == 0
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
@@ -0,0 +1,87 @@
+package keyloc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseKLID(t *testing.T) {
+	for s, expected := range map[string]klid{
+		"00000409": 0x00000409,
+		"00010409": 0x00010409,
+		"d0010407": 0xd0010407,
+		"E0010411": 0xe0010411,
+	} {
+		k, err := parseKLID(s)
+		if err != nil || k != expected {
+			t.Errorf("parseKLID(%q) = %v, %v, want %v", s, k, err, expected)
+		}
+	}
+	for _, s := range []string{"", "409", "0000040g", "000000409"} {
+		if _, err := parseKLID(s); !errors.Is(err, ErrParse) {
+			t.Errorf("parseKLID(%q) error = %v, want %v", s, err, ErrParse)
+		}
+	}
+}
+
+func TestPreloadSources(t *testing.T) {
+	// The registry of a user with US, German with the US Dvorak layout,
+	// Korean, Japanese IME and a layout whose Layout Id is missing:
+	//
+	//	HKCU\Keyboard Layout\Preload
+	//		1 = 00000409
+	//		2 = d0010407
+	//		3 = 00000412
+	//		4 = E0010411
+	//		5 = 00020409
+	//	HKCU\Keyboard Layout\Substitutes
+	//		d0010407 = 00010409
+	preload := []string{"00000409", "d0010407", "00000412", "E0010411", "00020409"}
+	substitutes := map[string]string{"d0010407": "00010409"}
+	layouts := map[klid]windowsLayout{
+		0x00000409: {text: "US"},
+		0x00010409: {text: "United States-Dvorak", id: "0002"},
+		0x00000412: {text: "Korean"},
+		0xe0010411: {text: "Japanese (Microsoft IME)"},
+	}
+	substitute := func(name string) (string, bool) {
+		s, ok := substitutes[name]
+		return s, ok
+	}
+	layout := func(k klid) windowsLayout { return layouts[k] }
+
+	sources, err := preloadSources("registry", preload, substitute, layout)
+	if err != nil {
+		t.Fatalf("preloadSources() returned an error: %v", err)
+	}
+	type source struct {
+		id, lang, name string
+		kind           Kind
+		isDefault      bool
+	}
+	var got []source
+	for _, src := range sources {
+		got = append(got, source{src.ID, src.Language, src.Name, src.Kind, src.Default})
+	}
+	expected := []source{
+		{"04090409", "en-US", "US", KindKeyboard, true},
+		{"F0020407", "de-DE", "United States-Dvorak", KindKeyboard, false},
+		{"04120412", "ko-KR", "Korean", KindKeyboard, false},
+		{"E0010411", "ja-JP", "Japanese (Microsoft IME)", KindInputMethod, false},
+		{"00020409", "en-US", "00020409", KindKeyboard, false},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("preloadSources() = %v, want %v", got, expected)
+	}
+	if h := sources[1].HKL; h.LangID != 0x0407 || h.Variant != 0x0002 {
+		t.Errorf("preloadSources()[1].HKL = %+v, want German input with layout variant 2", h)
+	}
+	if h := sources[4].HKL; h != (HKL{}) {
+		t.Errorf("preloadSources()[4].HKL = %+v, want the zero HKL for an unknown layout ID", h)
+	}
+
+	if _, err := preloadSources("registry", []string{"0409"}, substitute, layout); !errors.Is(err, ErrParse) {
+		t.Errorf("preloadSources() with a bad KLID error = %v, want %v", err, ErrParse)
+	}
+}
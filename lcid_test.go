@@ -0,0 +1,18 @@
+package keyloc
+
+import "testing"
+
+func TestLangIDTag(t *testing.T) {
+	for id, expected := range map[uint16]string{
+		0x0409: "en-US",
+		0x0412: "ko-KR",
+		0x0004: "zh-Hans",
+		// Unknown sublanguages fall back to their primary language
+		0x7c09: "en",
+		0x03ff: "",
+	} {
+		if got := langIDTag(id); got != expected {
+			t.Errorf("langIDTag(%#04x) = %q, want %q", id, got, expected)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package keyloc
+
+import (
+	"context"
+)
+
+// locale1Properties holds the properties of systemd-localed's
+// org.freedesktop.locale1 interface that describe the keyboard.
+type locale1Properties struct {
+	X11Layout      string
+	X11Variant     string
+	X11Model       string
+	X11Options     string
+	VConsoleKeymap string
+	Locale         []string
+}
+
+// queryLocale1 reads the org.freedesktop.locale1 properties over the bus at address.
+func queryLocale1(ctx context.Context, address string) (*locale1Properties, error) {
+	conn, err := dialDBus(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	props, err := conn.getAllProperties("org.freedesktop.locale1", "/org/freedesktop/locale1", "org.freedesktop.locale1")
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	p := &locale1Properties{}
+	p.X11Layout, _ = props["X11Layout"].(string)
+	p.X11Variant, _ = props["X11Variant"].(string)
+	p.X11Model, _ = props["X11Model"].(string)
+	p.X11Options, _ = props["X11Options"].(string)
+	p.VConsoleKeymap, _ = props["VConsoleKeymap"].(string)
+	if locale, ok := props["Locale"].([]any); ok {
+		for _, l := range locale {
+			if s, ok := l.(string); ok {
+				p.Locale = append(p.Locale, s)
+			}
+		}
+	}
+	return p, nil
+}
+
+// layouts returns the configured X11 layouts and their variants, in XKB
+// group order. Systems configured only for the console, with an empty
+// X11Layout, get the layout of their console keymap.
+func (p *locale1Properties) layouts() []x11Layout {
+	if p.X11Layout == "" && p.VConsoleKeymap != "" {
+		return []x11Layout{consoleKeymapLayout("", p.VConsoleKeymap)}
+	}
+	return splitX11Layouts(p.X11Layout, p.X11Variant)
+}
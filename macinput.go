@@ -0,0 +1,403 @@
+package keyloc
+
+import (
+	"strings"
+)
+
+// macKeyLayoutPrefix is the input source ID prefix of macOS keyboard layouts.
+const macKeyLayoutPrefix = "com.apple.keylayout."
+
+// macInputSources maps macOS input source IDs, as reported by Text Input
+// Sources, to language tags. Keyboard layouts are tagged with a region only
+// when they are specific to one, as "British" is. Input sources mapped to ""
+// are known to have no language, such as the emoji and character viewer.
+var macInputSources = map[string]string{
+	// English
+	"com.apple.keylayout.ABC":                "en",
+	"com.apple.keylayout.USExtended":         "en",
+	"com.apple.keylayout.US":                 "en-US",
+	"com.apple.keylayout.USInternational-PC": "en-US",
+	"com.apple.keylayout.British":            "en-GB",
+	"com.apple.keylayout.British-PC":         "en-GB",
+	"com.apple.keylayout.Australian":         "en-AU",
+	"com.apple.keylayout.Canadian":           "en-CA",
+	"com.apple.keylayout.Irish":              "en-IE",
+	"com.apple.keylayout.IrishExtended":      "ga",
+	"com.apple.keylayout.ABC-India":          "en-IN",
+	"com.apple.keylayout.Colemak":            "en",
+	"com.apple.keylayout.Dvorak":             "en",
+	"com.apple.keylayout.Dvorak-Left":        "en",
+	"com.apple.keylayout.Dvorak-Right":       "en",
+	"com.apple.keylayout.DVORAK-QWERTYCMD":   "en",
+
+	// Western European
+	"com.apple.keylayout.ABC-AZERTY":        "fr",
+	"com.apple.keylayout.French":            "fr",
+	"com.apple.keylayout.French-numerical":  "fr",
+	"com.apple.keylayout.French-PC":         "fr",
+	"com.apple.keylayout.Belgian":           "fr-BE",
+	"com.apple.keylayout.SwissFrench":       "fr-CH",
+	"com.apple.keylayout.Canadian-CSA":      "fr-CA",
+	"com.apple.keylayout.ABC-QWERTZ":        "de",
+	"com.apple.keylayout.German":            "de",
+	"com.apple.keylayout.German-DIN-2137":   "de",
+	"com.apple.keylayout.Austrian":          "de-AT",
+	"com.apple.keylayout.SwissGerman":       "de-CH",
+	"com.apple.keylayout.Spanish":           "es",
+	"com.apple.keylayout.Spanish-ISO":       "es",
+	"com.apple.keylayout.LatinAmerican":     "es-419",
+	"com.apple.keylayout.Italian":           "it",
+	"com.apple.keylayout.Italian-Pro":       "it",
+	"com.apple.keylayout.Portuguese":        "pt-PT",
+	"com.apple.keylayout.Brazilian":         "pt-BR",
+	"com.apple.keylayout.Brazilian-ABNT2":   "pt-BR",
+	"com.apple.keylayout.Brazilian-Pro":     "pt-BR",
+	"com.apple.keylayout.Dutch":             "nl",
+	"com.apple.keylayout.Welsh":             "cy",
+	"com.apple.keylayout.Maltese":           "mt",
+	"com.apple.keylayout.Icelandic":         "is",
+	"com.apple.keylayout.Faroese":           "fo",
+	"com.apple.keylayout.Danish":            "da",
+	"com.apple.keylayout.Norwegian":         "nb",
+	"com.apple.keylayout.NorwegianExtended": "nb",
+	"com.apple.keylayout.Swedish":           "sv",
+	"com.apple.keylayout.Swedish-Pro":       "sv",
+	"com.apple.keylayout.Finnish":           "fi",
+	"com.apple.keylayout.FinnishExtended":   "fi",
+	"com.apple.keylayout.Sami-PC":           "se",
+	"com.apple.keylayout.NorwegianSami-PC":  "se-NO",
+	"com.apple.keylayout.SwedishSami-PC":    "se-SE",
+	"com.apple.keylayout.FinnishSami-PC":    "se-FI",
+
+	// Central and Eastern European
+	"com.apple.keylayout.Polish":             "pl",
+	"com.apple.keylayout.PolishPro":          "pl",
+	"com.apple.keylayout.Czech":              "cs",
+	"com.apple.keylayout.Czech-QWERTY":       "cs",
+	"com.apple.keylayout.Slovak":             "sk",
+	"com.apple.keylayout.Slovak-QWERTY":      "sk",
+	"com.apple.keylayout.Hungarian":          "hu",
+	"com.apple.keylayout.Hungarian-QWERTY":   "hu",
+	"com.apple.keylayout.Romanian":           "ro",
+	"com.apple.keylayout.Romanian-Standard":  "ro",
+	"com.apple.keylayout.Croatian":           "hr",
+	"com.apple.keylayout.Croatian-PC":        "hr",
+	"com.apple.keylayout.Slovenian":          "sl",
+	"com.apple.keylayout.Serbian":            "sr-Cyrl",
+	"com.apple.keylayout.Serbian-Latin":      "sr-Latn",
+	"com.apple.keylayout.Macedonian":         "mk",
+	"com.apple.keylayout.Bulgarian":          "bg",
+	"com.apple.keylayout.Bulgarian-Phonetic": "bg",
+	"com.apple.keylayout.Estonian":           "et",
+	"com.apple.keylayout.Latvian":            "lv",
+	"com.apple.keylayout.Lithuanian":         "lt",
+	"com.apple.keylayout.Greek":              "el",
+	"com.apple.keylayout.GreekPolytonic":     "el",
+
+	// Cyrillic and Caucasus
+	"com.apple.keylayout.Russian":                "ru",
+	"com.apple.keylayout.RussianWin":             "ru",
+	"com.apple.keylayout.Russian-Phonetic":       "ru",
+	"com.apple.keylayout.Ukrainian":              "uk",
+	"com.apple.keylayout.Ukrainian-PC":           "uk",
+	"com.apple.keylayout.Byelorussian":           "be",
+	"com.apple.keylayout.Kazakh":                 "kk",
+	"com.apple.keylayout.Kyrgyz-Cyrillic":        "ky",
+	"com.apple.keylayout.Mongolian-Cyrillic":     "mn",
+	"com.apple.keylayout.Tajik-Cyrillic":         "tg",
+	"com.apple.keylayout.Uzbek-Cyrillic":         "uz-Cyrl",
+	"com.apple.keylayout.Georgian-QWERTY":        "ka",
+	"com.apple.keylayout.Armenian-HMQWERTY":      "hy",
+	"com.apple.keylayout.Armenian-WesternQWERTY": "hy",
+	"com.apple.keylayout.Azeri":                  "az",
+	"com.apple.keylayout.Turkmen":                "tk",
+
+	// Turkish
+	"com.apple.keylayout.Turkish":           "tr",
+	"com.apple.keylayout.Turkish-QWERTY":    "tr",
+	"com.apple.keylayout.Turkish-QWERTY-PC": "tr",
+	"com.apple.keylayout.Turkish-Standard":  "tr",
+
+	// Middle East
+	"com.apple.keylayout.Hebrew":            "he",
+	"com.apple.keylayout.Hebrew-PC":         "he",
+	"com.apple.keylayout.Hebrew-QWERTY":     "he",
+	"com.apple.keylayout.Arabic":            "ar",
+	"com.apple.keylayout.Arabic-AZERTY":     "ar",
+	"com.apple.keylayout.Arabic-PC":         "ar",
+	"com.apple.keylayout.Arabic-QWERTY":     "ar",
+	"com.apple.keylayout.Persian":           "fa",
+	"com.apple.keylayout.Persian-ISIRI2901": "fa",
+	"com.apple.keylayout.Afghan-Dari":       "fa-AF",
+	"com.apple.keylayout.Afghan-Pashto":     "ps",
+	"com.apple.keylayout.Afghan-Uzbek":      "uz-Arab",
+	"com.apple.keylayout.Kurdish-Sorani":    "ckb",
+	"com.apple.keylayout.Uyghur":            "ug",
+	"com.apple.keylayout.Urdu":              "ur",
+	"com.apple.keylayout.Jawi-QWERTY":       "ms-Arab",
+
+	// South Asia
+	"com.apple.keylayout.Devanagari":        "hi",
+	"com.apple.keylayout.Devanagari-QWERTY": "hi",
+	"com.apple.keylayout.Bangla":            "bn",
+	"com.apple.keylayout.Bangla-QWERTY":     "bn",
+	"com.apple.keylayout.Gujarati":          "gu",
+	"com.apple.keylayout.Gujarati-QWERTY":   "gu",
+	"com.apple.keylayout.Gurmukhi":          "pa",
+	"com.apple.keylayout.Gurmukhi-QWERTY":   "pa",
+	"com.apple.keylayout.Kannada":           "kn",
+	"com.apple.keylayout.Kannada-QWERTY":    "kn",
+	"com.apple.keylayout.Malayalam":         "ml",
+	"com.apple.keylayout.Malayalam-QWERTY":  "ml",
+	"com.apple.keylayout.Oriya":             "or",
+	"com.apple.keylayout.Oriya-QWERTY":      "or",
+	"com.apple.keylayout.Telugu":            "te",
+	"com.apple.keylayout.Telugu-QWERTY":     "te",
+	"com.apple.keylayout.Sinhala":           "si",
+	"com.apple.keylayout.Sinhala-QWERTY":    "si",
+	"com.apple.keylayout.Tibetan-Otani":     "bo",
+	"com.apple.keylayout.Tibetan-Wylie":     "bo",
+	"com.apple.keylayout.Tibetan-QWERTY":    "bo",
+
+	// Southeast Asia and the Pacific
+	"com.apple.keylayout.Thai":            "th",
+	"com.apple.keylayout.Thai-PattaChote": "th",
+	"com.apple.keylayout.Lao":             "lo",
+	"com.apple.keylayout.Khmer":           "km",
+	"com.apple.keylayout.Myanmar":         "my",
+	"com.apple.keylayout.Myanmar-QWERTY":  "my",
+	"com.apple.keylayout.Vietnamese":      "vi",
+	"com.apple.keylayout.Hawaiian":        "haw",
+	"com.apple.keylayout.Maori":           "mi",
+
+	// The Americas
+	"com.apple.keylayout.Cherokee-Nation":   "chr",
+	"com.apple.keylayout.Cherokee-QWERTY":   "chr",
+	"com.apple.keylayout.Inuktitut-Nunavut": "iu",
+	"com.apple.keylayout.Inuktitut-QWERTY":  "iu",
+
+	// Korean input method and its modes
+	"com.apple.inputmethod.Korean":                     "ko",
+	"com.apple.inputmethod.Korean.2SetKorean":          "ko",
+	"com.apple.inputmethod.Korean.3SetKorean":          "ko",
+	"com.apple.inputmethod.Korean.390Sebulshik":        "ko",
+	"com.apple.inputmethod.Korean.GongjinCheongRomaja": "ko",
+	"com.apple.inputmethod.Korean.HNCRomaja":           "ko",
+	"com.apple.keylayout.2SetHangul":                   "ko",
+
+	// Japanese input method; its Romaji mode types English
+	"com.apple.inputmethod.Kotoeri":                                      "ja",
+	"com.apple.inputmethod.Kotoeri.RomajiTyping":                         "ja",
+	"com.apple.inputmethod.Kotoeri.RomajiTyping.Japanese":                "ja",
+	"com.apple.inputmethod.Kotoeri.RomajiTyping.Japanese.Katakana":       "ja",
+	"com.apple.inputmethod.Kotoeri.RomajiTyping.Japanese.HalfWidthKana":  "ja",
+	"com.apple.inputmethod.Kotoeri.RomajiTyping.Japanese.FullWidthRoman": "ja",
+	"com.apple.inputmethod.Kotoeri.RomajiTyping.Roman":                   "en",
+	"com.apple.inputmethod.Kotoeri.KanaTyping":                           "ja",
+	"com.apple.inputmethod.Kotoeri.KanaTyping.Japanese":                  "ja",
+	"com.apple.inputmethod.Kotoeri.KanaTyping.Japanese.Katakana":         "ja",
+	"com.apple.inputmethod.Kotoeri.KanaTyping.Japanese.HalfWidthKana":    "ja",
+	"com.apple.inputmethod.Kotoeri.KanaTyping.Japanese.FullWidthRoman":   "ja",
+	"com.apple.inputmethod.Kotoeri.KanaTyping.Roman":                     "en",
+	"com.apple.inputmethod.Japanese":                                     "ja",
+	"com.apple.inputmethod.Japanese.Katakana":                            "ja",
+	"com.apple.inputmethod.Japanese.HalfWidthKana":                       "ja",
+	"com.apple.inputmethod.Japanese.FullWidthRoman":                      "ja",
+	"com.apple.inputmethod.Roman":                                        "en",
+
+	// Chinese input methods
+	"com.apple.inputmethod.SCIM":               "zh-Hans",
+	"com.apple.inputmethod.SCIM.ITABC":         "zh-Hans",
+	"com.apple.inputmethod.SCIM.Shuangpin":     "zh-Hans",
+	"com.apple.inputmethod.SCIM.WBX":           "zh-Hans",
+	"com.apple.inputmethod.SCIM.WBH":           "zh-Hans",
+	"com.apple.inputmethod.TCIM":               "zh-Hant",
+	"com.apple.inputmethod.TCIM.Cangjie":       "zh-Hant",
+	"com.apple.inputmethod.TCIM.Jianyi":        "zh-Hant",
+	"com.apple.inputmethod.TCIM.Pinyin":        "zh-Hant",
+	"com.apple.inputmethod.TCIM.Shuangpin":     "zh-Hant",
+	"com.apple.inputmethod.TCIM.WBH":           "zh-Hant",
+	"com.apple.inputmethod.TCIM.Zhuyin":        "zh-Hant",
+	"com.apple.inputmethod.TCIM.ZhuyinEten":    "zh-Hant",
+	"com.apple.inputmethod.TYIM":               "yue-Hant",
+	"com.apple.inputmethod.TYIM.Cangjie":       "yue-Hant",
+	"com.apple.inputmethod.TYIM.Phonetic":      "yue-Hant",
+	"com.apple.inputmethod.TYIM.Stroke":        "yue-Hant",
+	"com.apple.inputmethod.TYIM.Sucheng":       "yue-Hant",
+	"com.apple.inputmethod.ChineseHandwriting": "zh",
+
+	// Other input methods of macOS
+	"com.apple.inputmethod.VietnameseIM":                       "vi",
+	"com.apple.inputmethod.VietnameseIM.VietnameseSimpleTelex": "vi",
+	"com.apple.inputmethod.VietnameseIM.VietnameseTelex":       "vi",
+	"com.apple.inputmethod.VietnameseIM.VietnameseVNI":         "vi",
+	"com.apple.inputmethod.VietnameseIM.VietnameseVIQR":        "vi",
+	"com.apple.inputmethod.Tamil":                              "ta",
+	"com.apple.inputmethod.Tamil.AnjalIM":                      "ta",
+	"com.apple.inputmethod.Tamil.Tamil99":                      "ta",
+
+	// Popular third-party input methods
+	"com.google.inputmethod.Japanese":        "ja",
+	"com.google.inputmethod.Japanese.base":   "ja",
+	"com.google.inputmethod.Japanese.Roman":  "en",
+	"jp.sourceforge.inputmethod.aquaskk":     "ja",
+	"com.justsystems.inputmethod.atok34":     "ja",
+	"com.sogou.inputmethod.sogou":            "zh-Hans",
+	"com.baidu.inputmethod.BaiduIM":          "zh-Hans",
+	"com.tencent.inputmethod.QQInput":        "zh-Hans",
+	"im.rime.inputmethod.Squirrel":           "zh",
+	"org.openvanilla.inputmethod.McBopomofo": "zh-Hant",
+	"org.youknowone.inputmethod.Gureum":      "ko",
+
+	// Input sources without a language
+	"com.apple.CharacterPaletteIM":               "",
+	"com.apple.PressAndHold":                     "",
+	"com.apple.inputmethod.EmojiFunctionRowItem": "",
+	"com.apple.inputmethod.ironwood":             "",
+	"com.apple.KeyboardViewer":                   "",
+	"com.apple.50onPaletteIM":                    "",
+	"com.apple.inputmethod.AssistiveControl":     "",
+}
+
+// macKeyLayoutNames maps the "KeyboardLayout Name" of the HIToolbox
+// preferences to a keyboard layout ID where the ID is not the name without
+// spaces and dots, as "U.S. International - PC" is "USInternational-PC".
+var macKeyLayoutNames = map[string]string{
+	"Russian - PC":   "RussianWin",
+	"ABC - Extended": "USExtended",
+}
+
+// macKeyLayoutID returns the input source ID of the keyboard layout with
+// the given "KeyboardLayout Name", e.g. "com.apple.keylayout.US" for "U.S.".
+func macKeyLayoutID(name string) string {
+	if id, ok := macKeyLayoutNames[name]; ok {
+		return macKeyLayoutPrefix + id
+	}
+	return macKeyLayoutPrefix + strings.NewReplacer(" ", "", ".", "").Replace(name)
+}
+
+// macInputSourceLanguage returns the language of the macOS input source
+// with the given IDs, the most specific first, e.g. an input mode and the
+// bundle ID of its input method. IDs in macInputSources, or below one of
+// its bundle IDs, are exact. Otherwise the language is guessed from the
+// words in the IDs, with ConfidenceLow.
+func macInputSourceLanguage(ids ...string) (string, Confidence) {
+	for _, id := range ids {
+		for prefix := id; prefix != ""; {
+			if lang, ok := macInputSources[prefix]; ok {
+				return lang, ConfidenceHigh
+			}
+			i := strings.LastIndexByte(prefix, '.')
+			if i < 0 || strings.Count(prefix[:i], ".") < 2 {
+				break // Stop at bundle prefixes such as "com.apple.inputmethod"
+			}
+			prefix = prefix[:i]
+		}
+	}
+	for _, id := range ids {
+		if lang := guessMacLanguage(id); lang != "" {
+			return lang, ConfidenceLow
+		}
+	}
+	return "", ConfidenceLow
+}
+
+// guessMacLanguage guesses the language of a macOS input source from the
+// language names and writing systems its ID mentions.
+func guessMacLanguage(identifier string) string {
+	lowerIdentifier := strings.ToLower(identifier)
+	switch {
+	case strings.Contains(lowerIdentifier, "korean"), strings.Contains(lowerIdentifier, "hangul"):
+		return "ko"
+	case strings.Contains(lowerIdentifier, "u.s."), strings.Contains(lowerIdentifier, "abc"), strings.Contains(lowerIdentifier, "english"):
+		return "en"
+	case strings.Contains(lowerIdentifier, "russian"), strings.Contains(lowerIdentifier, "cyrillic"):
+		return "ru"
+	case strings.Contains(lowerIdentifier, "japanese"), strings.Contains(lowerIdentifier, "kana"), strings.Contains(lowerIdentifier, "romaji"):
+		return "ja"
+	case strings.Contains(lowerIdentifier, "french"):
+		return "fr"
+	case strings.Contains(lowerIdentifier, "german"):
+		return "de"
+	case strings.Contains(lowerIdentifier, "spanish"):
+		return "es"
+	case strings.Contains(lowerIdentifier, "chinese"), strings.Contains(lowerIdentifier, "pinyin"), strings.Contains(lowerIdentifier, "zhuyin"), strings.Contains(lowerIdentifier, "cangjie"):
+		return "zh"
+	case strings.Contains(lowerIdentifier, "italian"):
+		return "it"
+	case strings.Contains(lowerIdentifier, "portuguese"):
+		return "pt"
+	case strings.Contains(lowerIdentifier, "dutch"):
+		return "nl"
+	case strings.Contains(lowerIdentifier, "swedish"):
+		return "sv"
+	case strings.Contains(lowerIdentifier, "danish"):
+		return "da"
+	case strings.Contains(lowerIdentifier, "norwegian"):
+		return "no"
+	case strings.Contains(lowerIdentifier, "finnish"):
+		return "fi"
+	case strings.Contains(lowerIdentifier, "polish"):
+		return "pl"
+	case strings.Contains(lowerIdentifier, "turkish"):
+		return "tr"
+	case strings.Contains(lowerIdentifier, "arabic"):
+		return "ar"
+	case strings.Contains(lowerIdentifier, "hebrew"):
+		return "he"
+	case strings.Contains(lowerIdentifier, "greek"):
+		return "el"
+	case strings.Contains(lowerIdentifier, "thai"):
+		return "th"
+	case strings.Contains(lowerIdentifier, "vietnamese"):
+		return "vi"
+	case strings.Contains(lowerIdentifier, "hindi"):
+		return "hi"
+	case strings.Contains(lowerIdentifier, "bengali"):
+		return "bn"
+	case strings.Contains(lowerIdentifier, "punjabi"):
+		return "pa"
+	case strings.Contains(lowerIdentifier, "gujarati"):
+		return "gu"
+	case strings.Contains(lowerIdentifier, "tamil"):
+		return "ta"
+	case strings.Contains(lowerIdentifier, "telugu"):
+		return "te"
+	case strings.Contains(lowerIdentifier, "kannada"):
+		return "kn"
+	case strings.Contains(lowerIdentifier, "malayalam"):
+		return "ml"
+	case strings.Contains(lowerIdentifier, "indonesian"):
+		return "id"
+	case strings.Contains(lowerIdentifier, "malay"):
+		return "ms"
+	case strings.Contains(lowerIdentifier, "filipino"):
+		return "fil"
+	case strings.Contains(lowerIdentifier, "ukrainian"):
+		return "uk"
+	case strings.Contains(lowerIdentifier, "czech"):
+		return "cs"
+	case strings.Contains(lowerIdentifier, "slovak"):
+		return "sk"
+	case strings.Contains(lowerIdentifier, "hungarian"):
+		return "hu"
+	case strings.Contains(lowerIdentifier, "romanian"):
+		return "ro"
+	case strings.Contains(lowerIdentifier, "bulgarian"):
+		return "bg"
+	case strings.Contains(lowerIdentifier, "croatian"):
+		return "hr"
+	case strings.Contains(lowerIdentifier, "serbian"):
+		return "sr"
+	case strings.Contains(lowerIdentifier, "slovenian"):
+		return "sl"
+	case strings.Contains(lowerIdentifier, "estonian"):
+		return "et"
+	case strings.Contains(lowerIdentifier, "latvian"):
+		return "lv"
+	case strings.Contains(lowerIdentifier, "lithuanian"):
+		return "lt"
+	default:
+		return ""
+	}
+}
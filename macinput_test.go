@@ -0,0 +1,112 @@
+package keyloc
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestMacInputSourcesGolden checks every entry of macInputSources against
+// testdata/macinput.golden, which lists each input source ID with its
+// language. Run "go test -run MacInputSourcesGolden -update" after editing
+// the table, and review the diff.
+func TestMacInputSourcesGolden(t *testing.T) {
+	ids := make([]string, 0, len(macInputSources))
+	for id := range macInputSources {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	var b strings.Builder
+	for _, id := range ids {
+		lang := macInputSources[id]
+		if lang != "" {
+			tag, err := ParseTag(lang)
+			if err != nil {
+				t.Errorf("macInputSources[%q] = %q is not a valid tag: %v", id, lang, err)
+			} else if tag.String() != lang {
+				t.Errorf("macInputSources[%q] = %q, want the canonical %q", id, lang, tag.String())
+			}
+		}
+		if got, confidence := macInputSourceLanguage(id); got != lang || confidence != ConfidenceHigh {
+			t.Errorf("macInputSourceLanguage(%q) = %q, %v, want %q, high", id, got, confidence, lang)
+		}
+		fmt.Fprintf(&b, "%s\t%s\n", id, lang)
+	}
+
+	path := filepath.Join("testdata", "macinput.golden")
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != string(golden) {
+		gotLines, wantLines := strings.Split(got, "\n"), strings.Split(string(golden), "\n")
+		for i := 0; i < max(len(gotLines), len(wantLines)); i++ {
+			var g, w string
+			if i < len(gotLines) {
+				g = gotLines[i]
+			}
+			if i < len(wantLines) {
+				w = wantLines[i]
+			}
+			if g != w {
+				t.Fatalf("macInputSources differs from %s at line %d: got %q, want %q", path, i+1, g, w)
+			}
+		}
+	}
+}
+
+func TestMacInputSourceLanguage(t *testing.T) {
+	tests := []struct {
+		ids        []string
+		lang       string
+		confidence Confidence
+	}{
+		{[]string{"com.apple.keylayout.Colemak"}, "en", ConfidenceHigh},
+		{[]string{"com.apple.inputmethod.Kotoeri.RomajiTyping.Japanese"}, "ja", ConfidenceHigh},
+		{[]string{"com.apple.inputmethod.Kotoeri.RomajiTyping.Roman"}, "en", ConfidenceHigh},
+		// Unknown modes take the language of their input method
+		{[]string{"com.apple.inputmethod.Korean.NewMode", "com.apple.inputmethod.Korean"}, "ko", ConfidenceHigh},
+		{[]string{"com.apple.inputmethod.SCIM.NewMode"}, "zh-Hans", ConfidenceHigh},
+		{[]string{"com.apple.PressAndHold"}, "", ConfidenceHigh},
+		// Unknown layouts are guessed from their name
+		{[]string{"com.apple.keylayout.Lithuanian-Extended", "Lithuanian Extended"}, "lt", ConfidenceLow},
+		{[]string{"com.example.inputmethod.Malayalam"}, "ml", ConfidenceLow},
+		{[]string{"com.example.inputmethod.Malay"}, "ms", ConfidenceLow},
+		{[]string{"com.example.inputmethod.Unknown"}, "", ConfidenceLow},
+		// The bundle prefix itself is not an input source
+		{[]string{"com.apple.inputmethod"}, "", ConfidenceLow},
+	}
+	for _, tt := range tests {
+		lang, confidence := macInputSourceLanguage(tt.ids...)
+		if lang != tt.lang || confidence != tt.confidence {
+			t.Errorf("macInputSourceLanguage(%q) = %q, %v, want %q, %v", tt.ids, lang, confidence, tt.lang, tt.confidence)
+		}
+	}
+}
+
+func TestMacKeyLayoutID(t *testing.T) {
+	for name, expected := range map[string]string{
+		"U.S.":                    "com.apple.keylayout.US",
+		"ABC":                     "com.apple.keylayout.ABC",
+		"USInternational-PC":      "com.apple.keylayout.USInternational-PC",
+		"U.S. International - PC": "com.apple.keylayout.USInternational-PC",
+		"Swiss French":            "com.apple.keylayout.SwissFrench",
+		"Russian - PC":            "com.apple.keylayout.RussianWin",
+	} {
+		if got := macKeyLayoutID(name); got != expected {
+			t.Errorf("macKeyLayoutID(%q) = %q, want %q", name, got, expected)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package keyloc
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// macPreferencesPath returns the property list of a preferences domain of
+// the user, e.g. "com.apple.HIToolbox" or ".GlobalPreferences".
+func macPreferencesPath(domain string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "Library", "Preferences", domain+".plist")
+}
+
+// hiToolboxInputSource is an input source of the HIToolbox preferences,
+// such as
+//
+//	{
+//		"Bundle ID" = "com.apple.inputmethod.Korean";
+//		"Input Mode" = "com.apple.inputmethod.Korean.2SetKorean";
+//		InputSourceKind = "Input Mode";
+//	}
+type hiToolboxInputSource struct {
+	Kind       string // InputSourceKind, e.g. "Keyboard Layout" or "Input Mode"
+	BundleID   string
+	InputMode  string
+	LayoutName string // KeyboardLayout Name, e.g. "USInternational-PC"
+}
+
+// id returns the most specific identifier of the input source: the input
+// mode of an input method, its bundle ID, or the ID of a keyboard layout.
+func (s hiToolboxInputSource) id() string {
+	switch {
+	case s.InputMode != "":
+		return s.InputMode
+	case s.BundleID != "":
+		return s.BundleID
+	case s.LayoutName != "":
+		return macKeyLayoutID(s.LayoutName)
+	}
+	return ""
+}
+
+// kind returns the kind of the input source: layouts are keyboards, and
+// input modes and the input methods holding them are input methods.
+func (s hiToolboxInputSource) kind() Kind {
+	if s.Kind == "Keyboard Layout" || (s.Kind == "" && s.BundleID == "" && s.InputMode == "") {
+		return KindKeyboard
+	}
+	return KindInputMethod
+}
+
+// hiToolboxPrefs holds the input sources of com.apple.HIToolbox.plist, in
+// the order the user arranged them.
+type hiToolboxPrefs struct {
+	enabled []hiToolboxInputSource // AppleEnabledInputSources
+}
+
+// parseHIToolboxPrefs parses the HIToolbox preferences, in binary or XML
+// property list format.
+func parseHIToolboxPrefs(data []byte) (*hiToolboxPrefs, error) {
+	root, err := decodePlist(data)
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := root.(map[string]any)
+	if !ok {
+		return nil, parseError("com.apple.HIToolbox: top object is %T, want a dictionary", root)
+	}
+	enabled, err := hiToolboxInputSources(dict, "AppleEnabledInputSources")
+	if err != nil {
+		return nil, err
+	}
+	return &hiToolboxPrefs{enabled: enabled}, nil
+}
+
+// hiToolboxInputSources decodes the array of input sources under key.
+// A missing key is an empty list.
+func hiToolboxInputSources(dict map[string]any, key string) ([]hiToolboxInputSource, error) {
+	v, ok := dict[key]
+	if !ok {
+		return nil, nil
+	}
+	array, ok := v.([]any)
+	if !ok {
+		return nil, parseError("com.apple.HIToolbox: %s is %T, want an array", key, v)
+	}
+	sources := make([]hiToolboxInputSource, 0, len(array))
+	for _, entry := range array {
+		e, ok := entry.(map[string]any)
+		if !ok {
+			return nil, parseError("com.apple.HIToolbox: %s entry is %T, want a dictionary", key, entry)
+		}
+		s := hiToolboxInputSource{}
+		s.Kind, _ = e["InputSourceKind"].(string)
+		s.BundleID, _ = e["Bundle ID"].(string)
+		s.InputMode, _ = e["Input Mode"].(string)
+		s.LayoutName, _ = e["KeyboardLayout Name"].(string)
+		sources = append(sources, s)
+	}
+	return sources, nil
+}
+
+// globalPrefs holds the language settings of .GlobalPreferences.plist.
+type globalPrefs struct {
+	languages []string // AppleLanguages, the preferred language first
+}
+
+// parseGlobalPrefs parses the global preferences, in binary or XML
+// property list format.
+func parseGlobalPrefs(data []byte) (*globalPrefs, error) {
+	root, err := decodePlist(data)
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := root.(map[string]any)
+	if !ok {
+		return nil, parseError(".GlobalPreferences: top object is %T, want a dictionary", root)
+	}
+
+	p := &globalPrefs{}
+	if v, ok := dict["AppleLanguages"]; ok {
+		langs, ok := v.([]any)
+		if !ok {
+			return nil, parseError(".GlobalPreferences: AppleLanguages is %T, want an array", v)
+		}
+		for _, l := range langs {
+			if s, ok := l.(string); ok {
+				p.languages = append(p.languages, s)
+			}
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,54 @@
+package keyloc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseHIToolboxPrefs(t *testing.T) {
+	for _, name := range []string{"com.apple.HIToolbox.plist", "com.apple.HIToolbox.xml"} {
+		prefs, err := parseHIToolboxPrefs(readTestdata(t, name))
+		if err != nil {
+			t.Fatalf("parseHIToolboxPrefs(%s) returned an error: %v", name, err)
+		}
+		var ids []string
+		var kinds []Kind
+		for _, s := range prefs.enabled {
+			ids = append(ids, s.id())
+			kinds = append(kinds, s.kind())
+		}
+		expected := []string{
+			"com.apple.keylayout.USInternational-PC",
+			"com.apple.CharacterPaletteIM",
+			"com.apple.inputmethod.Korean",
+			"com.apple.inputmethod.Korean.2SetKorean",
+			"com.apple.keylayout.RussianWin",
+			"com.apple.PressAndHold",
+		}
+		if !reflect.DeepEqual(ids, expected) {
+			t.Errorf("parseHIToolboxPrefs(%s) enabled = %v, want %v", name, ids, expected)
+		}
+		expectedKinds := []Kind{KindKeyboard, KindInputMethod, KindInputMethod, KindInputMethod, KindKeyboard, KindInputMethod}
+		if !reflect.DeepEqual(kinds, expectedKinds) {
+			t.Errorf("parseHIToolboxPrefs(%s) enabled kinds = %v, want %v", name, kinds, expectedKinds)
+		}
+		if s := prefs.enabled[4]; s.Kind != "Keyboard Layout" || s.LayoutName != "Russian - PC" {
+			t.Errorf("parseHIToolboxPrefs(%s) enabled[4] = %+v", name, s)
+		}
+	}
+
+	if _, err := parseHIToolboxPrefs([]byte("<plist><dict><key>AppleEnabledInputSources</key><string>x</string></dict></plist>")); !errors.Is(err, ErrParse) {
+		t.Errorf("parseHIToolboxPrefs() with a bad AppleEnabledInputSources error = %v, want %v", err, ErrParse)
+	}
+}
+
+func TestParseGlobalPrefs(t *testing.T) {
+	prefs, err := parseGlobalPrefs(readTestdata(t, "GlobalPreferences.plist"))
+	if err != nil {
+		t.Fatalf("parseGlobalPrefs() returned an error: %v", err)
+	}
+	if expected := []string{"ko-KR", "en-US", "zh-Hant-TW"}; !reflect.DeepEqual(prefs.languages, expected) {
+		t.Errorf("parseGlobalPrefs() languages = %v, want %v", prefs.languages, expected)
+	}
+}
@@ -0,0 +1,374 @@
+package keyloc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// This file implements a reader for the binary and XML property list
+// formats that macOS stores preferences in. Values are decoded to
+// map[string]any, []any, string, int64, float64, bool, time.Time and []byte.
+
+// plistMaxDepth limits the nesting of containers, so that a binary plist
+// whose objects refer to each other cannot recurse forever.
+const plistMaxDepth = 64
+
+// plistEpoch is the reference date of plist dates.
+var plistEpoch = time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// decodePlist decodes a property list in binary or XML format.
+func decodePlist(data []byte) (any, error) {
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		return decodeBinaryPlist(data)
+	}
+	return decodeXMLPlist(data)
+}
+
+// binaryPlist is a binary property list being decoded.
+type binaryPlist struct {
+	data    []byte
+	offsets []uint64 // Object offsets, by object number
+	refSize int
+}
+
+// decodeBinaryPlist decodes a bplist00 property list. Its trailer, in the
+// last 32 bytes, locates the offset table and the top object.
+func decodeBinaryPlist(data []byte) (any, error) {
+	if len(data) < 8+32 {
+		return nil, parseError("plist: truncated binary plist")
+	}
+	trailer := data[len(data)-32:]
+	offsetSize, refSize := int(trailer[6]), int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:])
+	top := binary.BigEndian.Uint64(trailer[16:])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:])
+	if offsetSize < 1 || offsetSize > 8 || refSize < 1 || refSize > 8 {
+		return nil, parseError("plist: bad binary plist trailer")
+	}
+	if tableOffset > uint64(len(data)-32) || numObjects > (uint64(len(data)-32)-tableOffset)/uint64(offsetSize) {
+		return nil, parseError("plist: offset table out of range")
+	}
+
+	p := &binaryPlist{data: data[:len(data)-32], offsets: make([]uint64, numObjects), refSize: refSize}
+	for i := range p.offsets {
+		start := tableOffset + uint64(i*offsetSize)
+		p.offsets[i] = plistUint(data[start : start+uint64(offsetSize)])
+	}
+	return p.object(top, 0)
+}
+
+// plistUint decodes a big-endian unsigned integer of up to 8 bytes.
+func plistUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// object decodes the object with the given number.
+func (p *binaryPlist) object(ref uint64, depth int) (any, error) {
+	if depth > plistMaxDepth {
+		return nil, parseError("plist: objects nested too deeply")
+	}
+	if ref >= uint64(len(p.offsets)) || p.offsets[ref] >= uint64(len(p.data)) {
+		return nil, parseError("plist: object %d out of range", ref)
+	}
+	offset := p.offsets[ref]
+	marker := p.data[offset]
+	typ, info := marker>>4, int(marker&0x0f)
+	body := p.data[offset+1:]
+
+	switch typ {
+	case 0x0:
+		switch marker {
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		}
+		return nil, nil
+	case 0x1:
+		// 16-byte integers hold unsigned 64-bit values in their low half
+		size := 1 << info
+		if size > 16 || len(body) < size {
+			return nil, parseError("plist: bad integer at %d", offset)
+		}
+		v := body[:size]
+		if size == 16 {
+			v = v[8:]
+		}
+		return int64(plistUint(v)), nil
+	case 0x2:
+		size := 1 << info
+		if len(body) < size {
+			return nil, parseError("plist: bad real at %d", offset)
+		}
+		switch size {
+		case 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(body))), nil
+		case 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(body)), nil
+		}
+		return nil, parseError("plist: bad real at %d", offset)
+	case 0x3:
+		if marker != 0x33 || len(body) < 8 {
+			return nil, parseError("plist: bad date at %d", offset)
+		}
+		secs := math.Float64frombits(binary.BigEndian.Uint64(body))
+		return plistEpoch.Add(time.Duration(secs * float64(time.Second))), nil
+	case 0x8:
+		if len(body) < info+1 {
+			return nil, parseError("plist: bad UID at %d", offset)
+		}
+		return int64(plistUint(body[:info+1])), nil
+	}
+
+	// The remaining types have a length, which follows as an integer
+	// object when it does not fit in the marker.
+	n := uint64(info)
+	if info == 0x0f {
+		if len(body) < 1 || body[0]>>4 != 0x1 || 1<<(body[0]&0x0f) > 8 || len(body) < 1+1<<(body[0]&0x0f) {
+			return nil, parseError("plist: bad length at %d", offset)
+		}
+		size := 1 << (body[0] & 0x0f)
+		n = plistUint(body[1 : 1+size])
+		body = body[1+size:]
+	}
+	bytesOf := func(size uint64) ([]byte, error) {
+		if size > uint64(len(body)) {
+			return nil, parseError("plist: object at %d out of range", offset)
+		}
+		return body[:size], nil
+	}
+
+	switch typ {
+	case 0x4:
+		b, err := bytesOf(n)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.Clone(b), nil
+	case 0x5:
+		b, err := bytesOf(n)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case 0x6:
+		if n > math.MaxInt32 {
+			return nil, parseError("plist: string at %d out of range", offset)
+		}
+		b, err := bytesOf(2 * n)
+		if err != nil {
+			return nil, err
+		}
+		units := make([]uint16, n)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(b[2*i:])
+		}
+		return string(utf16.Decode(units)), nil
+	case 0xa, 0xc:
+		refs, err := p.refs(body, n, 1, offset)
+		if err != nil {
+			return nil, err
+		}
+		array := make([]any, len(refs))
+		for i, r := range refs {
+			if array[i], err = p.object(r, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	case 0xd:
+		refs, err := p.refs(body, n, 2, offset)
+		if err != nil {
+			return nil, err
+		}
+		dict := make(map[string]any, n)
+		for i := uint64(0); i < n; i++ {
+			key, err := p.object(refs[i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, parseError("plist: dictionary key of type %T at %d", key, offset)
+			}
+			if dict[k], err = p.object(refs[n+i], depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	}
+	return nil, parseError("plist: unknown object type 0x%02x at %d", marker, offset)
+}
+
+// refs decodes the per*n object references that follow a container marker.
+func (p *binaryPlist) refs(body []byte, n uint64, per int, offset uint64) ([]uint64, error) {
+	if n > uint64(len(body))/uint64(per*p.refSize) {
+		return nil, parseError("plist: container at %d out of range", offset)
+	}
+	refs := make([]uint64, n*uint64(per))
+	for i := range refs {
+		refs[i] = plistUint(body[i*p.refSize : (i+1)*p.refSize])
+	}
+	return refs, nil
+}
+
+// decodeXMLPlist decodes an XML property list, such as
+//
+//	<?xml version="1.0" encoding="UTF-8"?>
+//	<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+//	<plist version="1.0">
+//	<dict>
+//		<key>AppleLanguages</key>
+//		<array>
+//			<string>en-US</string>
+//		</array>
+//	</dict>
+//	</plist>
+func decodeXMLPlist(data []byte) (any, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, parseError("plist: no plist element")
+		}
+		if err != nil {
+			return nil, parseError("plist: %v", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if start.Name.Local != "plist" {
+				return nil, parseError("plist: root element <%s>, want <plist>", start.Name.Local)
+			}
+			v, ok, err := decodeXMLPlistValue(d, 0)
+			if err != nil {
+				return nil, err
+			}
+			if _, isKey := v.(xmlPlistKey); !ok || isKey {
+				return nil, parseError("plist: no value in <plist>")
+			}
+			return v, nil
+		}
+	}
+}
+
+// decodeXMLPlistValue decodes the next value element. It reports false
+// when it reaches the end of the enclosing element instead.
+func decodeXMLPlistValue(d *xml.Decoder, depth int) (any, bool, error) {
+	if depth > plistMaxDepth {
+		return nil, false, parseError("plist: elements nested too deeply")
+	}
+	var start xml.StartElement
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, false, parseError("plist: %v", err)
+		}
+		if _, ok := tok.(xml.EndElement); ok {
+			return nil, false, nil
+		}
+		if s, ok := tok.(xml.StartElement); ok {
+			start = s
+			break
+		}
+	}
+
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]any)
+		for {
+			key, ok, err := decodeXMLPlistValue(d, depth+1)
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				return dict, true, nil
+			}
+			k, isKey := key.(xmlPlistKey)
+			if !isKey {
+				return nil, false, parseError("plist: dictionary value without a key")
+			}
+			v, ok, err := decodeXMLPlistValue(d, depth+1)
+			if err != nil {
+				return nil, false, err
+			}
+			if _, isKey := v.(xmlPlistKey); !ok || isKey {
+				return nil, false, parseError("plist: key %q without a value", k)
+			}
+			dict[string(k)] = v
+		}
+	case "array":
+		array := []any{}
+		for {
+			v, ok, err := decodeXMLPlistValue(d, depth+1)
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				return array, true, nil
+			}
+			if _, isKey := v.(xmlPlistKey); isKey {
+				return nil, false, parseError("plist: key %q in an array", v)
+			}
+			array = append(array, v)
+		}
+	case "true", "false":
+		if err := d.Skip(); err != nil {
+			return nil, false, parseError("plist: %v", err)
+		}
+		return start.Name.Local == "true", true, nil
+	}
+
+	var text string
+	if err := d.DecodeElement(&text, &start); err != nil {
+		return nil, false, parseError("plist: %v", err)
+	}
+	switch start.Name.Local {
+	case "key":
+		return xmlPlistKey(text), true, nil
+	case "string":
+		return text, true, nil
+	case "integer":
+		text = strings.TrimSpace(text)
+		if v, err := strconv.ParseInt(text, 0, 64); err == nil {
+			return v, true, nil
+		}
+		v, err := strconv.ParseUint(text, 0, 64)
+		if err != nil {
+			return nil, false, parseError("plist: bad integer %q", text)
+		}
+		return int64(v), true, nil
+	case "real":
+		v, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, false, parseError("plist: bad real %q", text)
+		}
+		return v, true, nil
+	case "date":
+		v, err := time.Parse(time.RFC3339, strings.TrimSpace(text))
+		if err != nil {
+			return nil, false, parseError("plist: bad date %q", text)
+		}
+		return v, true, nil
+	case "data":
+		v, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+		if err != nil {
+			return nil, false, parseError("plist: bad data: %v", err)
+		}
+		return v, true, nil
+	}
+	return nil, false, parseError("plist: unknown element <%s>", start.Name.Local)
+}
+
+// xmlPlistKey distinguishes dictionary keys from string values.
+type xmlPlistKey string
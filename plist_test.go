@@ -0,0 +1,80 @@
+package keyloc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodePlist(t *testing.T) {
+	// The fixtures were written by Python's plistlib
+	binary, err := decodePlist(readTestdata(t, "com.apple.HIToolbox.plist"))
+	if err != nil {
+		t.Fatalf("decodePlist() of a binary plist returned an error: %v", err)
+	}
+	xml, err := decodePlist(readTestdata(t, "com.apple.HIToolbox.xml"))
+	if err != nil {
+		t.Fatalf("decodePlist() of an XML plist returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(binary, xml) {
+		t.Errorf("decodePlist() of the binary plist = %v, want the XML plist %v", binary, xml)
+	}
+
+	v, err := decodePlist(readTestdata(t, "GlobalPreferences.plist"))
+	if err != nil {
+		t.Fatalf("decodePlist() returned an error: %v", err)
+	}
+	dict := v.(map[string]any)
+	expected := map[string]any{
+		"AppleLanguages":                       []any{"ko-KR", "en-US", "zh-Hant-TW"},
+		"AppleKeyboardUIMode":                  int64(2),
+		"AppleMiniaturizeOnDoubleClick":        false,
+		"com.apple.trackpad.scaling":           1.5,
+		"TISPreviouslySelectedInputSourceName": "한국어",
+		"BlobData":                             []byte("\x00\x01\x02keyloc"),
+		"LargeNumber":                          int64(1 << 40),
+		"NSUserDictionaryReplacementItems":     []any{},
+		"AKLastEmailListRequestDateKey":        time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC),
+	}
+	for key, want := range expected {
+		if got := dict[key]; !reflect.DeepEqual(got, want) {
+			t.Errorf("decodePlist()[%q] = %#v, want %#v", key, got, want)
+		}
+	}
+}
+
+func TestDecodePlistErrors(t *testing.T) {
+	valid := readTestdata(t, "com.apple.HIToolbox.plist")
+	for name, data := range map[string][]byte{
+		"empty":            nil,
+		"truncated binary": valid[:len(valid)-10],
+		"bad trailer":      append(append([]byte(nil), valid[:len(valid)-32]...), make([]byte, 32)...),
+		"cycle": []byte("bplist00" +
+			"\xa1\x00" + // Array holding itself
+			"\x08" + // Offset table
+			"\x00\x00\x00\x00\x00\x00\x01\x01" +
+			"\x00\x00\x00\x00\x00\x00\x00\x01" +
+			"\x00\x00\x00\x00\x00\x00\x00\x00" +
+			"\x00\x00\x00\x00\x00\x00\x00\x0a"),
+		"not a plist":         []byte("<html></html>"),
+		"key without a value": []byte("<plist><dict><key>a</key></dict></plist>"),
+		"bad integer":         []byte("<plist><integer>x</integer></plist>"),
+		"unclosed":            []byte("<plist><array><string>a</string>"),
+	} {
+		if _, err := decodePlist(data); !errors.Is(err, ErrParse) {
+			t.Errorf("decodePlist() of %s error = %v, want %v", name, err, ErrParse)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package keyloc
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// Provider is a source of input sources, such as an operating system API or
+// a configuration file. Backends register their providers with Register.
+type Provider interface {
+	// Name returns the unique name of the provider, e.g. "locale1".
+	// It is reported as the Backend of every input source it returns.
+	Name() string
+	// Available reports whether the provider can be used on this system,
+	// e.g. whether the command or file it reads is present.
+	Available() bool
+	// InputSources returns the input sources known to the provider. It
+	// should give up when ctx is done.
+	InputSources(ctx context.Context) ([]InputSource, error)
+}
+
+// CurrentProvider is implemented by providers that know which input source is active.
+type CurrentProvider interface {
+	Provider
+	// CurrentInputSource returns the input source that is active right now.
+	CurrentInputSource(ctx context.Context) (InputSource, error)
+}
+
+// NotifyProvider is implemented by providers that can signal that their
+// input sources may have changed, so that Watch does not have to poll them.
+type NotifyProvider interface {
+	Provider
+	// Notify signals on the returned channel after each change. The channel
+	// is closed when ctx is done.
+	Notify(ctx context.Context) (<-chan struct{}, error)
+}
+
+// RootedProvider is implemented by providers that read configuration
+// files, so that they can inspect a filesystem tree other than that of the
+// running system, such as a mounted OS image.
+type RootedProvider interface {
+	Provider
+	// WithRoot returns a provider that reads every file relative to root.
+	WithRoot(root string) Provider
+}
+
+// rootPath returns path relative to root, or path itself if root is empty.
+func rootPath(root, path string) string {
+	if root == "" {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
+// rootPaths returns paths relative to root.
+func rootPaths(root string, paths []string) []string {
+	rooted := make([]string, len(paths))
+	for i, path := range paths {
+		rooted[i] = rootPath(root, path)
+	}
+	return rooted
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []Provider
+)
+
+// Register makes a provider available to clients created with New, after
+// the providers registered before it. It is meant to be called from init
+// functions, and panics if a provider with the same name is already registered.
+func Register(p Provider) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, r := range registry {
+		if r.Name() == p.Name() {
+			panic(fmt.Sprintf("keyloc: Register called twice for provider %q", p.Name()))
+		}
+	}
+	registry = append(registry, p)
+}
+
+// Providers returns the registered providers in the order they are queried.
+func Providers() []Provider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return append([]Provider(nil), registry...)
+}
+
+// Lookup returns the registered provider with the given name.
+func Lookup(name string) (Provider, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	for _, p := range registry {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
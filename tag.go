@@ -0,0 +1,347 @@
+package keyloc
+
+//go:generate go run gen_iso639.go
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tag is a parsed and canonicalized BCP 47 language tag.
+type Tag struct {
+	// Language is the lowercase primary language subtag, e.g. "zh".
+	Language string
+	// Script is the title-case ISO 15924 script subtag, e.g. "Hant".
+	Script string
+	// Region is the uppercase ISO 3166-1 or UN M.49 region subtag, e.g. "TW".
+	Region string
+	// Variants holds the lowercase variant subtags, e.g. ["valencia"].
+	Variants []string
+	// Extensions holds each extension sequence, e.g. ["u-co-pinyin"].
+	Extensions []string
+	// PrivateUse is the private use sequence, e.g. "x-foo".
+	PrivateUse string
+}
+
+// deprecatedLanguages maps deprecated or macrolanguage-individual codes to their preferred values.
+var deprecatedLanguages = map[string]string{
+	"iw":  "he",
+	"in":  "id",
+	"ji":  "yi",
+	"jw":  "jv",
+	"mo":  "ro",
+	"no":  "nb",
+	"tl":  "fil",
+	"cmn": "zh",
+	"arb": "ar",
+	"pes": "fa",
+	"zsm": "ms",
+	"swh": "sw",
+	"ekk": "et",
+	"lvs": "lv",
+	"khk": "mn",
+	"npi": "ne",
+	"ory": "or",
+	"uzn": "uz",
+	"azj": "az",
+	"plt": "mg",
+	"quz": "qu",
+	"ydd": "yi",
+	"gug": "gn",
+}
+
+// deprecatedRegions maps deprecated region codes to their replacements.
+var deprecatedRegions = map[string]string{
+	"BU": "MM",
+	"DD": "DE",
+	"FX": "FR",
+	"TP": "TL",
+	"YD": "YE",
+	"ZR": "CD",
+	"UK": "GB",
+}
+
+// irregularTags maps grandfathered and legacy whole tags to their preferred values.
+var irregularTags = map[string]string{
+	"i-klingon":  "tlh",
+	"i-navajo":   "nv",
+	"i-hak":      "hak",
+	"i-lux":      "lb",
+	"i-ami":      "ami",
+	"i-tao":      "tao",
+	"art-lojban": "jbo",
+	"zh-guoyu":   "zh",
+	"zh-hakka":   "hak",
+	"zh-xiang":   "hsn",
+	"zh-min-nan": "nan",
+	"sh":         "sr-Latn",
+}
+
+// posixModifiers maps POSIX locale modifiers (as in "sr_RS@latin") to script subtags.
+var posixModifiers = map[string]string{
+	"latin":      "Latn",
+	"cyrillic":   "Cyrl",
+	"devanagari": "Deva",
+	"arabic":     "Arab",
+}
+
+// ParseTag parses and canonicalizes a BCP 47 language tag.
+//
+// Underscores are accepted as separators, and POSIX locale names such as
+// "sr_RS.UTF-8@latin" are understood. Deprecated language and region codes
+// are replaced (e.g. "iw" becomes "he"), ISO 639-2 and 639-3 codes are
+// converted to their ISO 639-1 equivalents (e.g. "deu" becomes "de"), and
+// a script that is implied by the language is dropped (e.g. "en-Latn" becomes "en").
+func ParseTag(s string) (Tag, error) {
+	in := s
+	s = strings.TrimSpace(s)
+
+	var modifier string
+	if i := strings.IndexByte(s, '@'); i >= 0 {
+		s, modifier = s[:i], strings.ToLower(s[i+1:])
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i] // Drop a POSIX charset such as ".UTF-8"
+	}
+	s = strings.ToLower(strings.ReplaceAll(s, "_", "-"))
+	if s == "" {
+		return Tag{}, fmt.Errorf("%w: %q", ErrInvalidTag, in)
+	}
+	if pref, ok := irregularTags[s]; ok {
+		s = strings.ToLower(pref)
+	}
+
+	subtags := strings.Split(s, "-")
+	var t Tag
+	i := 0
+
+	// language, or a private use tag such as "x-klingon"
+	switch lang := subtags[0]; {
+	case lang == "x":
+		if err := checkPrivateUse(subtags, in); err != nil {
+			return Tag{}, err
+		}
+		t.PrivateUse = s
+		return t, nil
+	case isAlpha(lang) && (len(lang) >= 2 && len(lang) <= 3 || len(lang) >= 5 && len(lang) <= 8):
+		t.Language = lang
+		i++
+	default:
+		return Tag{}, fmt.Errorf("%w: %q", ErrInvalidTag, in)
+	}
+
+	// extlang: the extended language subtag replaces the primary language
+	if len(t.Language) <= 3 && i < len(subtags) && len(subtags[i]) == 3 && isAlpha(subtags[i]) {
+		t.Language = subtags[i]
+		i++
+		for i < len(subtags) && len(subtags[i]) == 3 && isAlpha(subtags[i]) {
+			i++
+		}
+	}
+
+	// script
+	if i < len(subtags) && len(subtags[i]) == 4 && isAlpha(subtags[i]) {
+		t.Script = strings.ToUpper(subtags[i][:1]) + subtags[i][1:]
+		i++
+	}
+
+	// region
+	if i < len(subtags) && (len(subtags[i]) == 2 && isAlpha(subtags[i]) || len(subtags[i]) == 3 && isDigit(subtags[i])) {
+		t.Region = strings.ToUpper(subtags[i])
+		i++
+	}
+
+	// variants
+	for i < len(subtags) && isVariant(subtags[i]) {
+		t.Variants = append(t.Variants, subtags[i])
+		i++
+	}
+
+	// extensions
+	for i < len(subtags) && len(subtags[i]) == 1 && subtags[i] != "x" {
+		start := i
+		i++
+		for i < len(subtags) && len(subtags[i]) >= 2 && len(subtags[i]) <= 8 && isAlphaNum(subtags[i]) {
+			i++
+		}
+		if i == start+1 {
+			return Tag{}, fmt.Errorf("%w: %q", ErrInvalidTag, in)
+		}
+		t.Extensions = append(t.Extensions, strings.Join(subtags[start:i], "-"))
+	}
+
+	// private use
+	if i < len(subtags) && subtags[i] == "x" {
+		if err := checkPrivateUse(subtags[i:], in); err != nil {
+			return Tag{}, err
+		}
+		t.PrivateUse = strings.Join(subtags[i:], "-")
+		i = len(subtags)
+	}
+
+	if i != len(subtags) {
+		return Tag{}, fmt.Errorf("%w: %q", ErrInvalidTag, in)
+	}
+
+	if script, ok := posixModifiers[modifier]; ok && t.Script == "" {
+		t.Script = script
+	}
+
+	t.canonicalize()
+	return t, nil
+}
+
+// canonicalize replaces deprecated and alternative codes with their preferred values.
+func (t *Tag) canonicalize() {
+	if pref, ok := iso639Alpha3[t.Language]; ok {
+		t.Language = pref
+	}
+	if pref, ok := deprecatedLanguages[t.Language]; ok {
+		t.Language = pref
+	}
+	if pref, ok := deprecatedRegions[t.Region]; ok {
+		t.Region = pref
+	}
+	if t.Script != "" && suppressScript(t.Language) == t.Script {
+		t.Script = ""
+	}
+}
+
+// String returns the canonical form of the tag, e.g. "zh-Hant-TW".
+func (t Tag) String() string {
+	parts := make([]string, 0, 3+len(t.Variants)+len(t.Extensions)+1)
+	for _, p := range []string{t.Language, t.Script, t.Region} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	parts = append(parts, t.Variants...)
+	parts = append(parts, t.Extensions...)
+	if t.PrivateUse != "" {
+		parts = append(parts, t.PrivateUse)
+	}
+	return strings.Join(parts, "-")
+}
+
+// MaximizedScript returns the script of the tag, inferring the most likely
+// one from the language and region when it is not given explicitly.
+func (t Tag) MaximizedScript() string {
+	if t.Script != "" {
+		return t.Script
+	}
+	return likelyScript(t.Language, t.Region)
+}
+
+// CanonicalizeTag returns the canonical form of a language tag, or the
+// lowercased input if it cannot be parsed.
+func CanonicalizeTag(s string) string {
+	t, err := ParseTag(s)
+	if err != nil {
+		return strings.ToLower(s)
+	}
+	return t.String()
+}
+
+// likelyScripts lists the usual script of languages that are not written in Latin script.
+var likelyScripts = map[string]string{
+	"am": "Ethi", "ar": "Arab", "as": "Beng", "be": "Cyrl", "bg": "Cyrl",
+	"bn": "Beng", "bo": "Tibt", "chr": "Cher", "ckb": "Arab", "dv": "Thaa",
+	"dz": "Tibt", "el": "Grek", "fa": "Arab", "gu": "Gujr", "he": "Hebr",
+	"hi": "Deva", "hy": "Armn", "iu": "Cans", "ja": "Jpan", "ka": "Geor",
+	"kk": "Cyrl", "km": "Khmr", "kn": "Knda", "ko": "Kore", "kok": "Deva",
+	"ks": "Arab", "ky": "Cyrl", "lo": "Laoo", "mk": "Cyrl", "ml": "Mlym",
+	"mn": "Cyrl", "mr": "Deva", "my": "Mymr", "ne": "Deva", "or": "Orya",
+	"pa": "Guru", "ps": "Arab", "ru": "Cyrl", "sa": "Deva", "sd": "Arab",
+	"si": "Sinh", "sr": "Cyrl", "syr": "Syrc", "ta": "Taml", "te": "Telu",
+	"tg": "Cyrl", "th": "Thai", "ti": "Ethi", "tt": "Cyrl", "ug": "Arab",
+	"uk": "Cyrl", "ur": "Arab", "yi": "Hebr", "zh": "Hans",
+}
+
+// regionalScripts overrides likelyScripts for specific language and region pairs.
+var regionalScripts = map[string]string{
+	"zh-TW": "Hant", "zh-HK": "Hant", "zh-MO": "Hant",
+	"sr-ME": "Latn", "pa-PK": "Arab", "uz-AF": "Arab",
+	"az-IR": "Arab", "mn-CN": "Mong", "sd-IN": "Deva",
+	"ks-IN": "Arab", "ha-NE": "Latn",
+}
+
+// multiScriptLanguages lists languages commonly written in more than one
+// script, whose script subtag is therefore never suppressed.
+var multiScriptLanguages = map[string]bool{
+	"az": true, "bs": true, "ha": true, "ks": true, "ku": true, "mn": true,
+	"ms": true, "pa": true, "sd": true, "sr": true, "tg": true, "uz": true,
+	"zh": true,
+}
+
+// likelyScript returns the most likely script for a language in a region.
+func likelyScript(lang, region string) string {
+	if lang == "" || lang == "und" {
+		return ""
+	}
+	if region != "" {
+		if script, ok := regionalScripts[lang+"-"+region]; ok {
+			return script
+		}
+	}
+	if script, ok := likelyScripts[lang]; ok {
+		return script
+	}
+	return "Latn"
+}
+
+// suppressScript returns the script that is redundant for a language, if any.
+func suppressScript(lang string) string {
+	if multiScriptLanguages[lang] {
+		return ""
+	}
+	return likelyScript(lang, "")
+}
+
+func checkPrivateUse(subtags []string, in string) error {
+	if len(subtags) < 2 {
+		return fmt.Errorf("%w: %q", ErrInvalidTag, in)
+	}
+	for _, p := range subtags[1:] {
+		if len(p) < 1 || len(p) > 8 || !isAlphaNum(p) {
+			return fmt.Errorf("%w: %q", ErrInvalidTag, in)
+		}
+	}
+	return nil
+}
+
+func isVariant(s string) bool {
+	switch {
+	case len(s) >= 5 && len(s) <= 8:
+		return isAlphaNum(s)
+	case len(s) == 4:
+		return isDigit(s[:1]) && isAlphaNum(s)
+	}
+	return false
+}
+
+func isAlpha(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'a' || s[i] > 'z' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isDigit(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isAlphaNum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < 'a' || s[i] > 'z') && (s[i] < '0' || s[i] > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
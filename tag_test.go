@@ -0,0 +1,92 @@
+package keyloc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"en", "en"},
+		{"EN-us", "en-US"},
+		{"en_GB", "en-GB"},
+		{"zh-hant-tw", "zh-Hant-TW"},
+		{"zh-Hans", "zh-Hans"},
+		{"sr-Latn-RS", "sr-Latn-RS"},
+		{"sr_RS@latin", "sr-Latn-RS"},
+		{"de_DE.UTF-8", "de-DE"},
+		{"en-Latn-US", "en-US"},
+		{"ru-Cyrl", "ru"},
+		{"iw", "he"},
+		{"in-ID", "id-ID"},
+		{"no", "nb"},
+		{"deu", "de"},
+		{"ger-AT", "de-AT"},
+		{"chi-TW", "zh-TW"},
+		{"cmn-Hans-CN", "zh-Hans-CN"},
+		{"zh-yue-HK", "yue-HK"},
+		{"sh", "sr-Latn"},
+		{"i-klingon", "tlh"},
+		{"es-419", "es-419"},
+		{"de-DD", "de-DE"},
+		{"ca-ES-valencia", "ca-ES-valencia"},
+		{"sl-rozaj-biske-1994", "sl-rozaj-biske-1994"},
+		{"de-CH-1901-u-co-phonebk-x-private", "de-CH-1901-u-co-phonebk-x-private"},
+		{"x-whatever", "x-whatever"},
+	}
+
+	for _, test := range tests {
+		tag, err := ParseTag(test.input)
+		if err != nil {
+			t.Errorf("ParseTag(%q) returned an error: %v", test.input, err)
+			continue
+		}
+		if got := tag.String(); got != test.expected {
+			t.Errorf("ParseTag(%q) = %q, want %q", test.input, got, test.expected)
+		}
+	}
+}
+
+func TestParseTagInvalid(t *testing.T) {
+	for _, input := range []string{"", "e", "1234", "en-a", "en-x", "en-US-!!", "toolonglanguage"} {
+		if _, err := ParseTag(input); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("ParseTag(%q) error = %v, want ErrInvalidTag", input, err)
+		}
+	}
+}
+
+func TestMatchTag(t *testing.T) {
+	tests := []struct {
+		have     string
+		want     string
+		mode     matchMode
+		expected bool
+	}{
+		{"zh-TW", "zh-Hans", matchLanguage, true},
+		{"zh-TW", "zh-Hans", matchScript, false},
+		{"zh-TW", "zh-Hant", matchScript, true},
+		{"zh-CN", "zh", matchScript, true},
+		{"zh-Hans", "zh-CN", matchExact, false},
+		{"zh-Hans-CN", "zh-CN", matchExact, true},
+		{"sr-Latn", "sr", matchScript, false},
+		{"sr-Cyrl-RS", "sr", matchScript, true},
+		{"pt-BR", "pt-PT", matchLanguage, true},
+		{"pt-BR", "pt-PT", matchExact, false},
+		{"pt-BR", "pt-br", matchExact, true},
+		{"he", "iw-IL", matchLanguage, true},
+		{"nb", "no", matchExact, true},
+	}
+
+	for _, test := range tests {
+		want, err := ParseTag(test.want)
+		if err != nil {
+			t.Fatalf("ParseTag(%q) returned an error: %v", test.want, err)
+		}
+		if got := matchTag(test.have, want, test.mode); got != test.expected {
+			t.Errorf("matchTag(%q, %q, %d) = %v, want %v", test.have, test.want, test.mode, got, test.expected)
+		}
+	}
+}
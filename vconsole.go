@@ -0,0 +1,184 @@
+package keyloc
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// vconsoleConfPath is read by systemd-vconsole-setup; KEYMAP names the
+	// console keymap, and newer systemd versions also keep the XKB
+	// settings there.
+	vconsoleConfPath = "/etc/vconsole.conf"
+	// debianKeyboardPath is the keyboard configuration of Debian and
+	// Ubuntu, shared by the console and X11.
+	debianKeyboardPath = "/etc/default/keyboard"
+	// kbdModelMapPath is systemd's table of console keymaps and their X11
+	// equivalents.
+	kbdModelMapPath = "/usr/share/systemd/kbd-model-map"
+)
+
+// consoleKeymaps maps console keymaps whose name differs from their X11
+// layout, for systems without kbd-model-map.
+var consoleKeymaps = map[string]x11Layout{
+	"uk":        {"gb", ""},
+	"sg":        {"ch", "de_nodeadkeys"},
+	"sg-latin1": {"ch", "de_nodeadkeys"},
+	"fr_CH":     {"ch", "fr"},
+	"cf":        {"ca", "fr-legacy"},
+	"trq":       {"tr", ""},
+	"trf":       {"tr", "f"},
+	"la-latin1": {"latam", ""},
+	"slovene":   {"si", ""},
+	"croat":     {"hr", ""},
+	"ko":        {"kr", ""},
+	"jp106":     {"jp", ""},
+	"sv-latin1": {"se", ""},
+	"se-latin1": {"se", ""},
+	"sr-cy":     {"rs", ""},
+	"sr-latin":  {"rs", "latin"},
+	"et":        {"ee", ""},
+	"br-abnt2":  {"br", ""},
+	"gr":        {"gr", ""},
+	"il":        {"il", ""},
+	"dvorak":    {"us", "dvorak"},
+	"bg_bds":    {"bg", ""},
+	"bg_pho":    {"bg", "phonetic"},
+	"hu101":     {"hu", "qwerty"},
+	"mac-us":    {"us", "mac"},
+}
+
+var (
+	kbdModelMapsMu sync.Mutex
+	kbdModelMaps   = make(map[string]map[string]x11Layout) // By root
+)
+
+// loadKbdModelMap returns the kbd-model-map of the system at root, reading
+// it the first time it is called for root.
+func loadKbdModelMap(root string) map[string]x11Layout {
+	kbdModelMapsMu.Lock()
+	defer kbdModelMapsMu.Unlock()
+	m, ok := kbdModelMaps[root]
+	if !ok {
+		if data, err := os.ReadFile(rootPath(root, kbdModelMapPath)); err == nil {
+			m = parseKbdModelMap(data)
+		}
+		kbdModelMaps[root] = m
+	}
+	return m
+}
+
+// consoleKeymapLayout returns the X11 layout that corresponds to a console
+// keymap, e.g. "de(nodeadkeys)" for "de-latin1-nodeadkeys" and "ru" for
+// "ru4". Keymaps are looked up in the kbd-model-map of systemd at root,
+// then in a built-in table, and otherwise split into a layout and a
+// variant that the XKB rules of root know about.
+func consoleKeymapLayout(root, keymap string) x11Layout {
+	if l, ok := loadKbdModelMap(root)[keymap]; ok {
+		return l
+	}
+	if l, ok := consoleKeymaps[keymap]; ok {
+		return l
+	}
+
+	// Drop the charset suffix of names like "bg_bds-utf8", then the
+	// revision of names like "ru4", "pl2" or "it2".
+	parts := strings.Split(strings.TrimSuffix(strings.TrimSuffix(keymap, ".map"), ".gz"), "-")
+	base := parts[0]
+	if l, ok := consoleKeymaps[base]; ok {
+		base = l.layout
+	}
+	if trimmed := strings.TrimRight(base, "0123456789"); trimmed != "" {
+		base = trimmed
+	}
+
+	l := x11Layout{layout: base}
+	rules := loadXKBRules(root).layouts
+	for _, p := range parts[1:] {
+		if _, ok := rules[x11Layout{base, p}.id()]; ok {
+			l.variant = p
+			break
+		}
+	}
+	return l
+}
+
+// parseKbdModelMap parses systemd's kbd-model-map, whose lines are
+//
+//	consolelayout  xlayout  xmodel  xvariant  xoptions
+//
+// with "-" for an empty field. Only the first of several X11 layouts is kept.
+func parseKbdModelMap(data []byte) map[string]x11Layout {
+	m := make(map[string]x11Layout)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		l := x11Layout{layout: strings.Split(fields[1], ",")[0]}
+		if v := strings.Split(fields[3], ",")[0]; v != "-" {
+			l.variant = v
+		}
+		m[fields[0]] = l
+	}
+	return m
+}
+
+// parseShellVars parses the KEY=value assignments of a shell-style
+// configuration file such as vconsole.conf. Inline " #" comments and the
+// quotes around values are removed; a "#" inside quotes is kept.
+func parseShellVars(data []byte) map[string]string {
+	vars := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+			// A comment starts only after the closing quote
+			if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+				value = value[1 : end+1]
+			}
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		vars[strings.TrimSpace(key)] = value
+	}
+	return vars
+}
+
+// vconsoleSources returns the console keymap and XKB layouts configured in
+// the contents of vconsole.conf and /etc/default/keyboard, either of which
+// may be nil, of the system at root.
+func vconsoleSources(backend, root string, vconsole, keyboard []byte) []InputSource {
+	var sources []InputSource
+	seen := make(map[string]bool)
+	add := func(src InputSource) {
+		if !seen[src.ID] {
+			seen[src.ID] = true
+			sources = append(sources, src)
+		}
+	}
+
+	vars := parseShellVars(vconsole)
+	if keymap := vars["KEYMAP"]; keymap != "" {
+		src := x11LayoutSource(backend, root, consoleKeymapLayout(root, keymap))
+		src.ID = keymap
+		add(src)
+	}
+	for _, l := range splitX11Layouts(vars["XKBLAYOUT"], vars["XKBVARIANT"]) {
+		add(x11LayoutSource(backend, root, l))
+	}
+
+	vars = parseShellVars(keyboard)
+	for _, l := range splitX11Layouts(vars["XKBLAYOUT"], vars["XKBVARIANT"]) {
+		add(x11LayoutSource(backend, root, l))
+	}
+	return sources
+}
@@ -0,0 +1,78 @@
+package keyloc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseShellVars(t *testing.T) {
+	vars := parseShellVars([]byte("# KEYBOARD CONFIGURATION FILE\n\nXKBMODEL=\"pc105\"\nXKBLAYOUT='us,de'\nexport XKBVARIANT=,nodeadkeys # German\nBACKSPACE=\"guess\"\nKEYMAP=\"de\" # German\nFONT='eurlatgr' #\nUNICODE=\"yes # no\"\n"))
+	expected := map[string]string{
+		"XKBMODEL":   "pc105",
+		"XKBLAYOUT":  "us,de",
+		"XKBVARIANT": ",nodeadkeys",
+		"BACKSPACE":  "guess",
+		"KEYMAP":     "de",
+		"FONT":       "eurlatgr",
+		"UNICODE":    "yes # no",
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("parseShellVars() = %v, want %v", vars, expected)
+	}
+}
+
+func TestParseKbdModelMap(t *testing.T) {
+	m := parseKbdModelMap([]byte("# consolelayout\t\txlayout\txmodel\t\txvariant\txoptions\nsg\t\t\tch\tpc105\t\tde_nodeadkeys\tterminate:ctrl_alt_bksp\nua-utf\t\t\tua,us\tpc105\t\t-\tterminate:ctrl_alt_bksp,grp:shifts_toggle\n"))
+	expected := map[string]x11Layout{
+		"sg":     {"ch", "de_nodeadkeys"},
+		"ua-utf": {"ua", ""},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("parseKbdModelMap() = %v, want %v", m, expected)
+	}
+}
+
+func TestConsoleKeymapLayout(t *testing.T) {
+	tests := []struct {
+		keymap   string
+		expected x11Layout
+	}{
+		{"us", x11Layout{"us", ""}},
+		{"uk", x11Layout{"gb", ""}},
+		{"ru4", x11Layout{"ru", ""}},
+		{"pl2", x11Layout{"pl", ""}},
+		{"de-latin1-nodeadkeys", x11Layout{"de", "nodeadkeys"}},
+		{"fr-latin9", x11Layout{"fr", "latin9"}},
+		{"cz-qwerty", x11Layout{"cz", "qwerty"}},
+		{"sg-latin1", x11Layout{"ch", "de_nodeadkeys"}},
+	}
+	for _, test := range tests {
+		if got := consoleKeymapLayout("", test.keymap); got != test.expected {
+			t.Errorf("consoleKeymapLayout(%q) = %v, want %v", test.keymap, got, test.expected)
+		}
+	}
+}
+
+func TestVconsoleSources(t *testing.T) {
+	sources := vconsoleSources("vconsole", "",
+		[]byte("KEYMAP=de-latin1-nodeadkeys\nFONT=eurlatgr\n"),
+		[]byte("XKBLAYOUT=\"de,ru\"\nXKBVARIANT=\"nodeadkeys,\"\n"),
+	)
+
+	var got [][2]string
+	for _, src := range sources {
+		got = append(got, [2]string{src.ID, src.Language})
+	}
+	expected := [][2]string{
+		{"de-latin1-nodeadkeys", "de-DE"},
+		{"de(nodeadkeys)", "de-DE"},
+		{"ru", "ru-RU"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("vconsoleSources() = %v, want %v", got, expected)
+	}
+
+	if sources := vconsoleSources("vconsole", "", nil, nil); len(sources) != 0 {
+		t.Errorf("vconsoleSources() without configuration = %v, want none", sources)
+	}
+}
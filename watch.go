@@ -0,0 +1,204 @@
+package keyloc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EventType describes what changed in an Event.
+type EventType int
+
+const (
+	// SourceAdded reports an input source that was added to the system.
+	SourceAdded EventType = iota + 1
+	// SourceRemoved reports an input source that was removed from the system.
+	SourceRemoved
+	// CurrentChanged reports that the active input source switched.
+	CurrentChanged
+)
+
+func (t EventType) String() string {
+	switch t {
+	case SourceAdded:
+		return "SourceAdded"
+	case SourceRemoved:
+		return "SourceRemoved"
+	case CurrentChanged:
+		return "CurrentChanged"
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event reports a change to the input sources of the system.
+type Event struct {
+	Type EventType
+	// Source is the added, removed or newly active input source.
+	Source InputSource
+	// Previous is the previously active input source of a CurrentChanged event.
+	Previous InputSource
+}
+
+// defaultPollInterval is how often Watch queries the system for changes.
+const defaultPollInterval = 2 * time.Second
+
+// Watch reports changes to the input sources until ctx is done, at which
+// point the returned channel is closed.
+func Watch(ctx context.Context) (<-chan Event, error) {
+	return New().Watch(ctx)
+}
+
+// Watch reports changes to the input sources until ctx is done, at which
+// point the returned channel is closed.
+//
+// Providers that implement NotifyProvider (such as the inotify based
+// Linux configuration providers) are queried again only after they signal
+// a change. All other providers, and the active input source, are polled.
+func (c *Client) Watch(ctx context.Context) (<-chan Event, error) {
+	providers := c.available()
+	if len(providers) == 0 {
+		return nil, ErrNoBackend
+	}
+
+	results, errs := c.queryProviders(ctx, providers)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := allFailed(providers, errs); err != nil {
+		return nil, err
+	}
+	sources := c.mergeSources(results)
+	current, currentErr := c.CurrentInputSource(ctx)
+
+	// Forward the notifications of each provider as its index, and poll
+	// the providers that cannot notify.
+	changed := make(chan int)
+	polled := make([]bool, len(providers))
+	for i, p := range providers {
+		if np, ok := p.(NotifyProvider); ok {
+			if ch, err := np.Notify(ctx); err == nil && ch != nil {
+				go forwardNotify(ctx, ch, i, changed)
+				continue
+			}
+		}
+		polled[i] = true
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(c.pollInterval)
+		defer ticker.Stop()
+
+		send := func(evs []Event) bool {
+			for _, ev := range evs {
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+
+		// requery refreshes the results of one provider. A failing
+		// provider keeps its previous results rather than reporting its
+		// input sources as removed.
+		requery := func(i int) {
+			if srcs, err := withTimeout(ctx, c.providerTimeout, providers[i].InputSources); err == nil {
+				results[i] = srcs
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case i := <-changed:
+				requery(i)
+			case <-ticker.C:
+				for i := range providers {
+					if polled[i] {
+						requery(i)
+					}
+				}
+			}
+
+			next := c.mergeSources(results)
+			evs := diffSources(sources, next)
+			sources = next
+			if !send(evs) {
+				return
+			}
+
+			cur, err := c.CurrentInputSource(ctx)
+			if err != nil {
+				continue
+			}
+			if currentErr != nil || sourceKey(cur) != sourceKey(current) {
+				ev := Event{Type: CurrentChanged, Source: cur, Previous: current}
+				current, currentErr = cur, nil
+				if !send([]Event{ev}) {
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// allFailed returns the joined provider errors if every provider failed, and nil otherwise.
+func allFailed(providers []Provider, errs []error) error {
+	var failed []error
+	for i, err := range errs {
+		if err == nil {
+			return nil
+		}
+		failed = append(failed, providerError(providers[i].Name(), err))
+	}
+	return errors.Join(failed...)
+}
+
+// forwardNotify sends index on changed for every notification received on ch.
+func forwardNotify(ctx context.Context, ch <-chan struct{}, index int, changed chan<- int) {
+	for range ch {
+		select {
+		case changed <- index:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sourceKey identifies an input source across queries.
+func sourceKey(src InputSource) string {
+	return src.Backend + "\x00" + src.ID + "\x00" + src.Language
+}
+
+// diffSources returns the events that turn the old list of input sources into the new one.
+func diffSources(old, new []InputSource) []Event {
+	oldKeys := make(map[string]bool, len(old))
+	for _, src := range old {
+		oldKeys[sourceKey(src)] = true
+	}
+	newKeys := make(map[string]bool, len(new))
+	for _, src := range new {
+		newKeys[sourceKey(src)] = true
+	}
+
+	var events []Event
+	for _, src := range old {
+		if !newKeys[sourceKey(src)] {
+			events = append(events, Event{Type: SourceRemoved, Source: src})
+		}
+	}
+	for _, src := range new {
+		if !oldKeys[sourceKey(src)] {
+			events = append(events, Event{Type: SourceAdded, Source: src})
+		}
+	}
+	return events
+}
@@ -0,0 +1,109 @@
+//go:build linux
+
+package keyloc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// watchedConfigs lists the keyboard configuration files watched on Linux,
+// by directory. An empty file list matches every file in the directory.
+var watchedConfigs = map[string][]string{
+	"/etc":                 {"vconsole.conf"},
+	"/etc/default":         {"keyboard"},
+	"/etc/X11/xorg.conf.d": nil,
+}
+
+// watchFile is watchFiles for a single file.
+func watchFile(ctx context.Context, path string) (<-chan struct{}, error) {
+	return watchFiles(ctx, map[string][]string{filepath.Dir(path): {filepath.Base(path)}})
+}
+
+// watchFiles uses inotify to signal on the returned channel whenever one of
+// the given files is written, created, moved or deleted. Signals are
+// coalesced, and the channel is closed when ctx is done.
+func watchFiles(ctx context.Context, dirs map[string][]string) (<-chan struct{}, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	f := os.NewFile(uintptr(fd), "inotify")
+
+	const mask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM
+	watches := make(map[int32][]string)
+	for dir, names := range dirs {
+		wd, err := syscall.InotifyAddWatch(fd, dir, mask)
+		if err != nil {
+			continue // The directory does not exist on this system
+		}
+		watches[int32(wd)] = names
+	}
+	if len(watches) == 0 {
+		f.Close()
+		return nil, errors.New("keyloc: no keyboard configuration directory to watch")
+	}
+
+	notify := make(chan struct{}, 1)
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+	go func() {
+		defer close(notify)
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			if !matchInotifyEvents(buf[:n], watches) {
+				continue
+			}
+			select {
+			case notify <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return notify, nil
+}
+
+// matchInotifyEvents reports whether any event in buf concerns a watched file.
+func matchInotifyEvents(buf []byte, watches map[int32][]string) bool {
+	for len(buf) >= syscall.SizeofInotifyEvent {
+		wd := int32(binary.NativeEndian.Uint32(buf[0:4]))
+		nameLen := int(binary.NativeEndian.Uint32(buf[12:16]))
+		end := syscall.SizeofInotifyEvent + nameLen
+		if end > len(buf) {
+			return false
+		}
+		name := string(buf[syscall.SizeofInotifyEvent:end])
+		for i := 0; i < len(name); i++ {
+			if name[i] == 0 {
+				name = name[:i]
+				break
+			}
+		}
+		buf = buf[end:]
+
+		names, ok := watches[wd]
+		if !ok {
+			continue
+		}
+		if len(names) == 0 {
+			return true
+		}
+		for _, n := range names {
+			if n == filepath.Base(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
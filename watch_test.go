@@ -0,0 +1,47 @@
+package keyloc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffSources(t *testing.T) {
+	us := newInputSource("us", "en", "English (US)", "test")
+	kr := newInputSource("kr", "ko", "Korean", "test")
+	ru := newInputSource("ru", "ru", "Russian", "test")
+
+	got := diffSources([]InputSource{us, kr}, []InputSource{us, ru})
+	expected := []Event{
+		{Type: SourceRemoved, Source: kr},
+		{Type: SourceAdded, Source: ru},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("diffSources() = %v, want %v", got, expected)
+	}
+
+	if got := diffSources([]InputSource{us, kr}, []InputSource{kr, us}); len(got) != 0 {
+		t.Errorf("diffSources() of reordered sources = %v, want no events", got)
+	}
+}
+
+func TestWatchPollInterval(t *testing.T) {
+	p := &stubProvider{name: "stub", available: true, sources: []InputSource{newInputSource("us", "en-US", "English (US)", "stub")}}
+	for _, d := range []time.Duration{0, -time.Second} {
+		c := New(WithProviders(p), WithPollInterval(d))
+		if c.pollInterval != defaultPollInterval {
+			t.Errorf("WithPollInterval(%v) set the interval to %v, want the default %v", d, c.pollInterval, defaultPollInterval)
+		}
+
+		// Watch must not panic in its goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		events, err := c.Watch(ctx)
+		if err != nil {
+			t.Fatalf("Watch() with WithPollInterval(%v) returned an error: %v", d, err)
+		}
+		cancel()
+		for range events {
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package keyloc
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Wayland compositors own the keyboard configuration of their session, and
+// report it over their IPC sockets.
+
+const (
+	swayIPCMagic     = "i3-ipc"
+	swayIPCGetInputs = 100
+)
+
+// compositorMaxReply bounds the size of compositor replies, which list a
+// handful of devices and never come close to it.
+const compositorMaxReply = 16 << 20
+
+// swaySocketPath returns the IPC socket of the running sway session.
+func swaySocketPath() string {
+	return os.Getenv("SWAYSOCK")
+}
+
+// hyprlandSocketPath returns the request socket of the running Hyprland
+// instance, or "" if there is none.
+func hyprlandSocketPath() string {
+	sig := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")
+	if sig == "" {
+		return ""
+	}
+	// Hyprland moved its sockets from /tmp to the runtime directory in v0.40
+	dirs := []string{"/tmp/hypr"}
+	if runtime := os.Getenv("XDG_RUNTIME_DIR"); runtime != "" {
+		dirs = append([]string{filepath.Join(runtime, "hypr")}, dirs...)
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, sig, ".socket.sock")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// swayInput is an input device as reported by sway's get_inputs.
+type swayInput struct {
+	Identifier           string   `json:"identifier"`
+	Type                 string   `json:"type"`
+	XKBLayoutNames       []string `json:"xkb_layout_names"`
+	XKBActiveLayoutIndex int      `json:"xkb_active_layout_index"`
+}
+
+// swayInputSources returns the layouts of the keyboards known to the sway
+// instance listening at path, and the index of the active one. Sway
+// reports layouts by description, such as "German (no dead keys)".
+func swayInputSources(ctx context.Context, backend, path string) ([]InputSource, int, error) {
+	reply, err := compositorRequest(ctx, path, func(conn net.Conn) ([]byte, error) {
+		msg := make([]byte, 0, len(swayIPCMagic)+8)
+		msg = append(msg, swayIPCMagic...)
+		msg = binary.NativeEndian.AppendUint32(msg, 0)
+		msg = binary.NativeEndian.AppendUint32(msg, swayIPCGetInputs)
+		if _, err := conn.Write(msg); err != nil {
+			return nil, err
+		}
+
+		header := make([]byte, len(swayIPCMagic)+8)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return nil, err
+		}
+		if string(header[:len(swayIPCMagic)]) != swayIPCMagic {
+			return nil, parseError("sway ipc: bad reply magic %q", header[:len(swayIPCMagic)])
+		}
+		size := binary.NativeEndian.Uint32(header[len(swayIPCMagic):])
+		if typ := binary.NativeEndian.Uint32(header[len(swayIPCMagic)+4:]); typ != swayIPCGetInputs {
+			return nil, parseError("sway ipc: reply of type %d, want %d", typ, swayIPCGetInputs)
+		}
+		if size > compositorMaxReply {
+			return nil, parseError("sway ipc: reply of %d bytes", size)
+		}
+		payload := make([]byte, size)
+		_, err := io.ReadFull(conn, payload)
+		return payload, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var inputs []swayInput
+	if err := json.Unmarshal(reply, &inputs); err != nil {
+		return nil, 0, parseError("sway get_inputs: %v", err)
+	}
+
+	// Keyboards usually share one configuration; the first keyboard with
+	// layouts decides which one is active.
+	var sources []InputSource
+	active := -1
+	seen := make(map[string]int)
+	for _, input := range inputs {
+		if input.Type != "keyboard" || len(input.XKBLayoutNames) == 0 {
+			continue
+		}
+		for i, name := range input.XKBLayoutNames {
+			if _, ok := seen[name]; !ok {
+				seen[name] = len(sources)
+				sources = append(sources, compositorLayoutSource(backend, name))
+			}
+			if active < 0 && i == input.XKBActiveLayoutIndex {
+				active = seen[name]
+			}
+		}
+	}
+	return sources, max(active, 0), nil
+}
+
+// hyprlandKeyboard is a keyboard as reported by Hyprland's devices request.
+type hyprlandKeyboard struct {
+	Name         string `json:"name"`
+	Layout       string `json:"layout"`
+	Variant      string `json:"variant"`
+	ActiveKeymap string `json:"active_keymap"`
+	Main         bool   `json:"main"`
+}
+
+// hyprlandInputSources returns the layouts of the main keyboard of the
+// Hyprland instance listening at path, and the index of the active one.
+func hyprlandInputSources(ctx context.Context, backend, path string) ([]InputSource, int, error) {
+	reply, err := compositorRequest(ctx, path, func(conn net.Conn) ([]byte, error) {
+		if _, err := io.WriteString(conn, "j/devices"); err != nil {
+			return nil, err
+		}
+		// Hyprland closes the connection after replying
+		reply, err := io.ReadAll(io.LimitReader(conn, compositorMaxReply+1))
+		if err == nil && len(reply) > compositorMaxReply {
+			return nil, parseError("hyprland: reply of more than %d bytes", compositorMaxReply)
+		}
+		return reply, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var devices struct {
+		Keyboards []hyprlandKeyboard `json:"keyboards"`
+	}
+	if err := json.Unmarshal(reply, &devices); err != nil {
+		return nil, 0, parseError("hyprland devices: %v", err)
+	}
+	if len(devices.Keyboards) == 0 {
+		return nil, 0, nil
+	}
+
+	kb := devices.Keyboards[0]
+	for _, k := range devices.Keyboards {
+		if k.Main {
+			kb = k
+			break
+		}
+	}
+
+	sources := x11LayoutSources(backend, "", splitX11Layouts(kb.Layout, kb.Variant))
+	active := 0
+	for i, src := range sources {
+		// The active keymap is reported by description
+		if src.Name == kb.ActiveKeymap {
+			active = i
+			break
+		}
+	}
+	return sources, active, nil
+}
+
+// compositorLayoutSource returns a layout reported by description as an input source.
+func compositorLayoutSource(backend, name string) InputSource {
+	if l, ok := lookupXKBName("", name); ok {
+		return x11LayoutSource(backend, "", l)
+	}
+	return newInputSource(name, "", name, backend)
+}
+
+// compositorRequest connects to the unix socket at path and runs exchange
+// on the connection, giving up when ctx is done.
+func compositorRequest(ctx context.Context, path string, exchange func(net.Conn) ([]byte, error)) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("keyloc: no compositor socket")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	reply, err := exchange(conn)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return reply, nil
+}
@@ -0,0 +1,144 @@
+package keyloc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// A get_inputs reply recorded from sway 1.9, trimmed to the interesting fields.
+const testSwayInputs = `[
+  {"identifier": "1:1:AT_Translated_Set_2_keyboard", "name": "AT Translated Set 2 keyboard", "type": "keyboard",
+   "xkb_layout_names": ["English (US)", "German (no dead keys)", "Korean"], "xkb_active_layout_index": 1,
+   "xkb_active_layout_name": "German (no dead keys)", "libinput": {"send_events": "enabled"}, "vendor": 1, "product": 1},
+  {"identifier": "1267:12377:ELAN1300:00_04F3:3059_Touchpad", "name": "ELAN1300:00 04F3:3059 Touchpad", "type": "touchpad",
+   "libinput": {"send_events": "enabled", "tap": "enabled"}, "vendor": 1267, "product": 12377},
+  {"identifier": "1:1:Video_Bus", "name": "Video Bus", "type": "keyboard",
+   "xkb_layout_names": ["English (US)", "German (no dead keys)", "Korean"], "xkb_active_layout_index": 0,
+   "xkb_active_layout_name": "English (US)", "libinput": {"send_events": "enabled"}, "vendor": 1, "product": 1}
+]`
+
+// A devices reply recorded from Hyprland 0.41, trimmed to the interesting fields.
+const testHyprlandDevices = `{
+"mice": [{"address": "0x5581b2b0", "name": "elan1300:00-04f3:3059-touchpad", "defaultSpeed": 0.0}],
+"keyboards": [
+  {"address": "0x55a1c510", "name": "power-button", "rules": "", "model": "", "layout": "us", "variant": "", "options": "", "active_keymap": "English (US)", "main": false},
+  {"address": "0x55a1d0f0", "name": "at-translated-set-2-keyboard", "rules": "", "model": "", "layout": "us,de", "variant": "intl,nodeadkeys", "options": "grp:alt_shift_toggle", "active_keymap": "German (no dead keys)", "main": true}
+],
+"tablets": [],
+"touch": [],
+"switches": []
+}`
+
+// serveUnix serves each connection to a new unix socket with handle.
+func serveUnix(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ipc.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return path
+}
+
+func TestSwayInputSources(t *testing.T) {
+	path := serveUnix(t, func(conn net.Conn) {
+		header := make([]byte, 14)
+		if _, err := io.ReadFull(conn, header); err != nil || string(header[:6]) != "i3-ipc" {
+			return
+		}
+		typ := binary.NativeEndian.Uint32(header[10:])
+		reply := append([]byte("i3-ipc"), make([]byte, 8)...)
+		binary.NativeEndian.PutUint32(reply[6:], uint32(len(testSwayInputs)))
+		binary.NativeEndian.PutUint32(reply[10:], typ)
+		conn.Write(append(reply, testSwayInputs...))
+	})
+
+	sources, active, err := swayInputSources(context.Background(), "sway", path)
+	if err != nil {
+		t.Fatalf("swayInputSources() returned an error: %v", err)
+	}
+	expected := []struct{ id, lang string }{
+		{"us", "en-US"},
+		{"de(nodeadkeys)", "de-DE"},
+		{"kr", "ko-KR"},
+	}
+	if len(sources) != len(expected) {
+		t.Fatalf("swayInputSources() returned %d sources, want %d: %+v", len(sources), len(expected), sources)
+	}
+	for i, want := range expected {
+		if sources[i].ID != want.id || sources[i].Language != want.lang || sources[i].Backend != "sway" {
+			t.Errorf("swayInputSources()[%d] = %+v, want %s in %s", i, sources[i], want.id, want.lang)
+		}
+	}
+	if active != 1 {
+		t.Errorf("swayInputSources() active = %d, want 1", active)
+	}
+
+	path = serveUnix(t, func(conn net.Conn) {
+		io.ReadFull(conn, make([]byte, 14))
+		reply := append([]byte("i3-ipc"), make([]byte, 8)...)
+		binary.NativeEndian.PutUint32(reply[6:], 0xffffffff)
+		binary.NativeEndian.PutUint32(reply[10:], swayIPCGetInputs)
+		conn.Write(reply)
+	})
+	if _, _, err := swayInputSources(context.Background(), "sway", path); !errors.Is(err, ErrParse) {
+		t.Errorf("swayInputSources() with an oversized reply error = %v, want %v", err, ErrParse)
+	}
+}
+
+func TestHyprlandInputSources(t *testing.T) {
+	path := serveUnix(t, func(conn net.Conn) {
+		request := make([]byte, 64)
+		n, _ := conn.Read(request)
+		if string(request[:n]) != "j/devices" {
+			io.WriteString(conn, "unknown request")
+			return
+		}
+		io.WriteString(conn, testHyprlandDevices)
+	})
+
+	sources, active, err := hyprlandInputSources(context.Background(), "hyprland", path)
+	if err != nil {
+		t.Fatalf("hyprlandInputSources() returned an error: %v", err)
+	}
+	if len(sources) != 2 || sources[0].ID != "us(intl)" || sources[1].ID != "de(nodeadkeys)" || sources[1].Language != "de-DE" {
+		t.Errorf("hyprlandInputSources() = %+v, want us(intl) and de(nodeadkeys)", sources)
+	}
+	if active != 1 {
+		t.Errorf("hyprlandInputSources() active = %d, want 1", active)
+	}
+}
+
+func TestCompositorRequestCancel(t *testing.T) {
+	path := serveUnix(t, func(conn net.Conn) {
+		io.Copy(io.Discard, conn) // Never reply
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := hyprlandInputSources(ctx, "hyprland", path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("hyprlandInputSources() on a silent socket error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, _, err := swayInputSources(context.Background(), "sway", ""); err == nil {
+		t.Error("swayInputSources() without a socket succeeded")
+	}
+}
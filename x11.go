@@ -0,0 +1,472 @@
+package keyloc
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// This file implements the small subset of the X11 protocol that keyloc
+// needs: connecting to a local display, authenticating with
+// MIT-MAGIC-COOKIE-1, reading the XKB rules names from the root window and
+// asking the XKEYBOARD extension for the active group.
+
+// errNoDisplay is returned when $DISPLAY does not name a local X server.
+var errNoDisplay = errors.New("keyloc: no local X11 display")
+
+// x11SocketDir is where X servers create their listening sockets.
+var x11SocketDir = "/tmp/.X11-unix"
+
+// X11 core requests and atoms.
+const (
+	x11InternAtom     = 16
+	x11GetProperty    = 20
+	x11QueryExtension = 98
+	x11AtomString     = 31
+)
+
+// XKEYBOARD extension requests.
+const (
+	xkbUseExtension = 0
+	xkbGetState     = 4
+	xkbUseCoreKbd   = 0x100
+)
+
+// x11MaxReply bounds the size of the replies keyloc reads, which is well
+// above the largest property it asks for.
+const x11MaxReply = 1 << 20
+
+// Xauthority address families.
+const (
+	xauthFamilyLocal = 256
+	xauthFamilyWild  = 65535
+)
+
+// x11Display is a parsed $DISPLAY value.
+type x11Display struct {
+	number string
+	paths  []string // Unix sockets to try, abstract ones with a leading "@"
+}
+
+// parseX11Display parses a display name of the form
+// [protocol/][host]:number[.screen]. Only local displays are supported:
+// an empty host or "unix", which are reached through the abstract and
+// filesystem sockets of the display, or the socket path itself, as XQuartz
+// uses it.
+func parseX11Display(display string) (x11Display, error) {
+	i := strings.LastIndexByte(display, ':')
+	if i < 0 {
+		return x11Display{}, fmt.Errorf("%w: %q", errNoDisplay, display)
+	}
+	host, number := display[:i], display[i+1:]
+	number, _, _ = strings.Cut(number, ".")
+	if _, err := strconv.ParseUint(number, 10, 16); err != nil {
+		return x11Display{}, fmt.Errorf("%w: %q", errNoDisplay, display)
+	}
+	if protocol, rest, ok := strings.Cut(host, "/"); ok && protocol != "" {
+		if protocol != "unix" && protocol != "local" {
+			return x11Display{}, fmt.Errorf("%w: %q", errNoDisplay, display)
+		}
+		host = rest
+	}
+
+	switch {
+	case host == "" || host == "unix":
+		path := filepath.Join(x11SocketDir, "X"+number)
+		return x11Display{number: number, paths: []string{"@" + path, path}}, nil
+	case strings.HasPrefix(host, "/"):
+		return x11Display{number: number, paths: []string{host}}, nil
+	}
+	return x11Display{}, fmt.Errorf("%w: %q", errNoDisplay, display)
+}
+
+// xauthEntry is an entry of an Xauthority file.
+type xauthEntry struct {
+	family  uint16
+	address string
+	number  string
+	name    string
+	data    []byte
+}
+
+// xauthorityPath returns the Xauthority file of the user.
+func xauthorityPath() string {
+	if path := os.Getenv("XAUTHORITY"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".Xauthority")
+}
+
+// parseXauthority parses the entries of an Xauthority file. Each entry is
+// a big-endian family followed by the length-prefixed address, display
+// number, authorization name and data.
+func parseXauthority(data []byte) ([]xauthEntry, error) {
+	var entries []xauthEntry
+	field := func() ([]byte, error) {
+		if len(data) < 2 {
+			return nil, parseError("xauthority: truncated entry")
+		}
+		n := int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+n {
+			return nil, parseError("xauthority: truncated entry")
+		}
+		f := data[2 : 2+n]
+		data = data[2+n:]
+		return f, nil
+	}
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, parseError("xauthority: truncated entry")
+		}
+		e := xauthEntry{family: binary.BigEndian.Uint16(data)}
+		data = data[2:]
+		var fields [4][]byte
+		for i := range fields {
+			f, err := field()
+			if err != nil {
+				return nil, err
+			}
+			fields[i] = f
+		}
+		e.address, e.number, e.name, e.data = string(fields[0]), string(fields[1]), string(fields[2]), fields[3]
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// xauthCookie returns the MIT-MAGIC-COOKIE-1 of the local display number
+// from entries, or nil if there is none.
+func xauthCookie(entries []xauthEntry, hostname, number string) []byte {
+	for _, e := range entries {
+		if e.family != xauthFamilyWild && (e.family != xauthFamilyLocal || e.address != hostname) {
+			continue
+		}
+		if e.number != "" && e.number != number {
+			continue
+		}
+		if e.name == "MIT-MAGIC-COOKIE-1" {
+			return e.data
+		}
+	}
+	return nil
+}
+
+// x11Error is an error reply from the X server.
+type x11Error struct {
+	Code  byte
+	Major byte
+	Minor uint16
+}
+
+func (e *x11Error) Error() string {
+	return fmt.Sprintf("x11: error %d in request %d.%d", e.Code, e.Major, e.Minor)
+}
+
+// x11Conn is a connection to an X server. Requests are sent in
+// little-endian byte order, which the server answers in.
+type x11Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	seq  uint16
+	root uint32
+	stop func() bool
+}
+
+// dialX11 connects to the local X server named by display and completes
+// the connection setup, authenticating with the cookie from the user's
+// Xauthority file if there is one.
+func dialX11(ctx context.Context, display string) (*x11Conn, error) {
+	d, err := parseX11Display(display)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	var conn net.Conn
+	for _, path := range d.paths {
+		conn, err = dialer.DialContext(ctx, "unix", path)
+		if err == nil {
+			break
+		}
+	}
+	if conn == nil {
+		return nil, fmt.Errorf("%w: %v", errNoDisplay, err)
+	}
+
+	// Unblock pending reads and writes once ctx is done
+	c := &x11Conn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		stop: context.AfterFunc(ctx, func() { conn.Close() }),
+	}
+	var cookie []byte
+	if data, err := os.ReadFile(xauthorityPath()); err == nil {
+		if entries, err := parseXauthority(data); err == nil {
+			hostname, _ := os.Hostname()
+			cookie = xauthCookie(entries, hostname, d.number)
+		}
+	}
+	if err := c.setup(cookie); err != nil {
+		c.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// setup sends the connection setup request and reads the root window of
+// the first screen from the reply.
+func (c *x11Conn) setup(cookie []byte) error {
+	var authName string
+	if cookie != nil {
+		authName = "MIT-MAGIC-COOKIE-1"
+	}
+	req := []byte{'l', 0}
+	req = binary.LittleEndian.AppendUint16(req, 11)
+	req = binary.LittleEndian.AppendUint16(req, 0)
+	req = binary.LittleEndian.AppendUint16(req, uint16(len(authName)))
+	req = binary.LittleEndian.AppendUint16(req, uint16(len(cookie)))
+	req = append(req, 0, 0)
+	req = x11Pad(append(req, authName...))
+	req = x11Pad(append(req, cookie...))
+	if _, err := c.conn.Write(req); err != nil {
+		return err
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(c.r, header); err != nil {
+		return err
+	}
+	n := 4 * int(binary.LittleEndian.Uint16(header[6:]))
+	if n > x11MaxReply {
+		return parseError("x11: setup reply of %d bytes", n)
+	}
+	reply := make([]byte, n)
+	if _, err := io.ReadFull(c.r, reply); err != nil {
+		return err
+	}
+	switch header[0] {
+	case 1:
+	case 0:
+		reason := reply[:min(int(header[1]), len(reply))]
+		return fmt.Errorf("x11: connection refused: %s", strings.TrimSpace(string(reason)))
+	default:
+		return errors.New("x11: connection requires further authentication")
+	}
+
+	// The fixed part of the setup is followed by the vendor string, the
+	// pixmap formats and the screens, each starting with its root window.
+	if len(reply) < 32 {
+		return parseError("x11: setup reply of %d bytes", len(reply))
+	}
+	vendorLen := int(binary.LittleEndian.Uint16(reply[16:]))
+	screens, formats := reply[20], int(reply[21])
+	offset := 32 + (vendorLen+3)&^3 + 8*formats
+	if screens == 0 || len(reply) < offset+4 {
+		return parseError("x11: setup reply without screens")
+	}
+	c.root = binary.LittleEndian.Uint32(reply[offset:])
+	return nil
+}
+
+func (c *x11Conn) Close() error {
+	c.stop()
+	return c.conn.Close()
+}
+
+// request sends a request and returns its reply. The length field of req
+// is filled in.
+func (c *x11Conn) request(req []byte) ([]byte, error) {
+	req = x11Pad(req)
+	binary.LittleEndian.PutUint16(req[2:], uint16(len(req)/4))
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, err
+	}
+	c.seq++
+
+	for {
+		reply := make([]byte, 32)
+		if _, err := io.ReadFull(c.r, reply); err != nil {
+			return nil, err
+		}
+		// Replies and generic events carry additional data
+		if reply[0] == 1 || reply[0]&0x7f == 35 {
+			n := 4 * uint64(binary.LittleEndian.Uint32(reply[4:]))
+			if n > x11MaxReply {
+				return nil, parseError("x11: reply of %d bytes", 32+n)
+			}
+			extra := make([]byte, n)
+			if _, err := io.ReadFull(c.r, extra); err != nil {
+				return nil, err
+			}
+			reply = append(reply, extra...)
+		}
+		if reply[0] > 1 || binary.LittleEndian.Uint16(reply[2:]) != c.seq {
+			continue // Events and replies to other requests
+		}
+		if reply[0] == 0 {
+			return nil, &x11Error{Code: reply[1], Major: reply[10], Minor: binary.LittleEndian.Uint16(reply[8:])}
+		}
+		return reply, nil
+	}
+}
+
+// internAtom returns the atom with the given name, or 0 if the server does
+// not know it.
+func (c *x11Conn) internAtom(name string) (uint32, error) {
+	req := []byte{x11InternAtom, 1, 0, 0}
+	req = binary.LittleEndian.AppendUint16(req, uint16(len(name)))
+	req = append(req, 0, 0)
+	reply, err := c.request(append(req, name...))
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(reply[8:]), nil
+}
+
+// getStringProperty returns a STRING property of a window, or nil if the
+// window does not have it.
+func (c *x11Conn) getStringProperty(window, property uint32) ([]byte, error) {
+	req := []byte{x11GetProperty, 0, 0, 0}
+	req = binary.LittleEndian.AppendUint32(req, window)
+	req = binary.LittleEndian.AppendUint32(req, property)
+	req = binary.LittleEndian.AppendUint32(req, x11AtomString)
+	req = binary.LittleEndian.AppendUint32(req, 0)
+	req = binary.LittleEndian.AppendUint32(req, 1<<16) // In 4-byte units
+	reply, err := c.request(req)
+	if err != nil {
+		return nil, err
+	}
+	format, typ := reply[1], binary.LittleEndian.Uint32(reply[8:])
+	if typ == 0 {
+		return nil, nil
+	}
+	if typ != x11AtomString || format != 8 {
+		return nil, parseError("x11: property has type %d and format %d, want STRING", typ, format)
+	}
+	n := int(binary.LittleEndian.Uint32(reply[16:]))
+	if len(reply) < 32+n {
+		return nil, parseError("x11: truncated property of %d bytes", n)
+	}
+	return reply[32 : 32+n], nil
+}
+
+// xkbGroup returns the effective group of the core keyboard, or -1 if the
+// server does not support XKB.
+func (c *x11Conn) xkbGroup() (int, error) {
+	const name = "XKEYBOARD"
+	req := []byte{x11QueryExtension, 0, 0, 0}
+	req = binary.LittleEndian.AppendUint16(req, uint16(len(name)))
+	req = append(req, 0, 0)
+	reply, err := c.request(append(req, name...))
+	if err != nil {
+		return 0, err
+	}
+	if reply[8] == 0 {
+		return -1, nil
+	}
+	major := reply[9]
+
+	// XKB requests are refused until the client announces its version
+	req = []byte{major, xkbUseExtension, 0, 0}
+	req = binary.LittleEndian.AppendUint16(req, 1)
+	req = binary.LittleEndian.AppendUint16(req, 0)
+	if reply, err = c.request(req); err != nil {
+		return 0, err
+	}
+	if reply[1] == 0 {
+		return -1, nil
+	}
+
+	req = []byte{major, xkbGetState, 0, 0}
+	req = binary.LittleEndian.AppendUint16(req, xkbUseCoreKbd)
+	req = append(req, 0, 0)
+	if reply, err = c.request(req); err != nil {
+		return 0, err
+	}
+	return int(reply[12]), nil
+}
+
+// x11Keyboard is the XKB configuration of an X server, as stored in the
+// _XKB_RULES_NAMES property of its root window, and the active group.
+type x11Keyboard struct {
+	rules   string
+	model   string
+	layout  string
+	variant string
+	options string
+	group   int // Active XKB group, or -1 if the server does not support XKB
+}
+
+// layouts returns the configured layouts, in group order.
+func (k *x11Keyboard) layouts() []x11Layout {
+	return splitX11Layouts(k.layout, k.variant)
+}
+
+// queryX11Keyboard reads the keyboard configuration of the X server named
+// by display.
+func queryX11Keyboard(ctx context.Context, display string) (*x11Keyboard, error) {
+	c, err := dialX11(ctx, display)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	kb, err := c.keyboard()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return kb, nil
+}
+
+func (c *x11Conn) keyboard() (*x11Keyboard, error) {
+	atom, err := c.internAtom("_XKB_RULES_NAMES")
+	if err != nil {
+		return nil, err
+	}
+	var value []byte
+	if atom != 0 {
+		if value, err = c.getStringProperty(c.root, atom); err != nil {
+			return nil, err
+		}
+	}
+	if value == nil {
+		return nil, parseError("x11: no _XKB_RULES_NAMES on the root window")
+	}
+
+	// The property holds the rules, model, layout, variant and options,
+	// each terminated by a NUL
+	var names [5]string
+	copy(names[:], strings.Split(string(value), "\x00"))
+	kb := &x11Keyboard{rules: names[0], model: names[1], layout: names[2], variant: names[3], options: names[4]}
+
+	if kb.group, err = c.xkbGroup(); err != nil {
+		return nil, err
+	}
+	return kb, nil
+}
+
+// x11Pad pads b to a multiple of 4 bytes.
+func x11Pad(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
@@ -0,0 +1,278 @@
+package keyloc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeX11 is a minimal X server that completes the connection setup and
+// answers the requests queryX11Keyboard makes.
+type fakeX11 struct {
+	cookie []byte // Required MIT-MAGIC-COOKIE-1, if any
+	rules  string // Value of _XKB_RULES_NAMES, if any
+	xkb    bool
+	group  byte
+	huge   bool // Announce a property of 16 GiB
+}
+
+const (
+	fakeX11Root      = 0x1e2
+	fakeX11RulesAtom = 300
+	fakeX11XKBMajor  = 135
+)
+
+func (s fakeX11) serve(conn net.Conn) {
+	order := binary.LittleEndian
+	setup := make([]byte, 12)
+	if _, err := io.ReadFull(conn, setup); err != nil || setup[0] != 'l' {
+		return
+	}
+	auth := make([]byte, (int(order.Uint16(setup[6:]))+3)&^3+(int(order.Uint16(setup[8:]))+3)&^3)
+	if _, err := io.ReadFull(conn, auth); err != nil {
+		return
+	}
+	nameLen := int(order.Uint16(setup[6:]))
+	cookie := auth[(nameLen+3)&^3:][:order.Uint16(setup[8:])]
+	if s.cookie != nil && (string(auth[:nameLen]) != "MIT-MAGIC-COOKIE-1" || string(cookie) != string(s.cookie)) {
+		reason := x11Pad([]byte("No protocol specified\n"))
+		reply := []byte{0, 22, 11, 0, 0, 0}
+		reply = order.AppendUint16(reply, uint16(len(reason)/4))
+		conn.Write(append(reply, reason...))
+		return
+	}
+
+	// Fixed setup data, the vendor, one pixmap format and one screen
+	info := make([]byte, 32)
+	order.PutUint16(info[16:], 4)
+	info[20], info[21] = 1, 1
+	info = append(info, "fake"...)
+	info = append(info, make([]byte, 8)...)
+	screen := make([]byte, 40)
+	order.PutUint32(screen, fakeX11Root)
+	info = append(info, screen...)
+	reply := []byte{1, 0, 11, 0, 0, 0}
+	reply = order.AppendUint16(reply, uint16(len(info)/4))
+	conn.Write(append(reply, info...))
+
+	seq := uint16(0)
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		body := make([]byte, 4*int(order.Uint16(header[2:]))-4)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		seq++
+
+		reply := make([]byte, 32)
+		reply[0] = 1
+		order.PutUint16(reply[2:], seq)
+		switch header[0] {
+		case x11InternAtom:
+			// An unrelated event may arrive before any reply
+			event := make([]byte, 32)
+			event[0] = 2
+			order.PutUint16(event[2:], seq-1)
+			conn.Write(event)
+
+			name := string(body[4:][:order.Uint16(body)])
+			if name == "_XKB_RULES_NAMES" && s.rules != "" {
+				order.PutUint32(reply[8:], fakeX11RulesAtom)
+			}
+		case x11GetProperty:
+			if order.Uint32(body) == fakeX11Root && order.Uint32(body[4:]) == fakeX11RulesAtom {
+				value := x11Pad([]byte(s.rules))
+				reply[1] = 8
+				order.PutUint32(reply[4:], uint32(len(value)/4))
+				order.PutUint32(reply[8:], x11AtomString)
+				order.PutUint32(reply[16:], uint32(len(s.rules)))
+				reply = append(reply, value...)
+			}
+			if s.huge {
+				order.PutUint32(reply[4:], 0xffffffff)
+			}
+		case x11QueryExtension:
+			if string(body[4:][:order.Uint16(body)]) == "XKEYBOARD" && s.xkb {
+				reply[8], reply[9] = 1, fakeX11XKBMajor
+			}
+		case fakeX11XKBMajor:
+			switch header[1] {
+			case xkbUseExtension:
+				reply[1] = 1
+			case xkbGetState:
+				reply[12] = s.group
+			}
+		default:
+			reply[0], reply[1] = 0, 1 // BadRequest
+			reply[10] = header[0]
+		}
+		conn.Write(reply)
+	}
+}
+
+// appendXauth appends an Xauthority entry to b.
+func appendXauth(b []byte, family uint16, address, number, name string, data []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, family)
+	for _, f := range [][]byte{[]byte(address), []byte(number), []byte(name), data} {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(f)))
+		b = append(b, f...)
+	}
+	return b
+}
+
+func TestParseX11Display(t *testing.T) {
+	tests := []struct {
+		display string
+		number  string
+		paths   []string
+	}{
+		{":0", "0", []string{"@/tmp/.X11-unix/X0", "/tmp/.X11-unix/X0"}},
+		{":1.0", "1", []string{"@/tmp/.X11-unix/X1", "/tmp/.X11-unix/X1"}},
+		{"unix:2", "2", []string{"@/tmp/.X11-unix/X2", "/tmp/.X11-unix/X2"}},
+		{"unix/:3", "3", []string{"@/tmp/.X11-unix/X3", "/tmp/.X11-unix/X3"}},
+		{"/private/tmp/com.apple.launchd.abc/org.xquartz:0", "0", []string{"/private/tmp/com.apple.launchd.abc/org.xquartz"}},
+	}
+	for _, tt := range tests {
+		d, err := parseX11Display(tt.display)
+		if err != nil {
+			t.Errorf("parseX11Display(%q) returned an error: %v", tt.display, err)
+			continue
+		}
+		if d.number != tt.number || !reflect.DeepEqual(d.paths, tt.paths) {
+			t.Errorf("parseX11Display(%q) = %+v, want %s %v", tt.display, d, tt.number, tt.paths)
+		}
+	}
+
+	for _, display := range []string{"", "0", ":x", "localhost:10.0", "tcp/host:0"} {
+		if _, err := parseX11Display(display); !errors.Is(err, errNoDisplay) {
+			t.Errorf("parseX11Display(%q) error = %v, want %v", display, err, errNoDisplay)
+		}
+	}
+}
+
+func TestXauthCookie(t *testing.T) {
+	var data []byte
+	data = appendXauth(data, 0, "\x7f\x00\x00\x01", "0", "MIT-MAGIC-COOKIE-1", []byte("tcp"))
+	data = appendXauth(data, xauthFamilyLocal, "otherhost", "0", "MIT-MAGIC-COOKIE-1", []byte("other"))
+	data = appendXauth(data, xauthFamilyLocal, "myhost", "1", "MIT-MAGIC-COOKIE-1", []byte("one"))
+	data = appendXauth(data, xauthFamilyLocal, "myhost", "0", "XDM-AUTHORIZATION-1", []byte("xdm"))
+	data = appendXauth(data, xauthFamilyLocal, "myhost", "0", "MIT-MAGIC-COOKIE-1", []byte("zero"))
+	data = appendXauth(data, xauthFamilyWild, "", "", "MIT-MAGIC-COOKIE-1", []byte("wild"))
+
+	entries, err := parseXauthority(data)
+	if err != nil {
+		t.Fatalf("parseXauthority() returned an error: %v", err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("parseXauthority() returned %d entries, want 6", len(entries))
+	}
+	for _, tt := range []struct{ hostname, number, cookie string }{
+		{"myhost", "0", "zero"},
+		{"myhost", "1", "one"},
+		{"myhost", "2", "wild"},
+		{"otherhost", "0", "other"},
+	} {
+		if got := string(xauthCookie(entries, tt.hostname, tt.number)); got != tt.cookie {
+			t.Errorf("xauthCookie(%s, %s) = %q, want %q", tt.hostname, tt.number, got, tt.cookie)
+		}
+	}
+
+	if _, err := parseXauthority(data[:len(data)-1]); !errors.Is(err, ErrParse) {
+		t.Errorf("parseXauthority() of a truncated file error = %v, want %v", err, ErrParse)
+	}
+}
+
+func TestQueryX11Keyboard(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("no hostname: %v", err)
+	}
+	cookie := []byte("0123456789abcdef")
+	xauth := filepath.Join(t.TempDir(), "Xauthority")
+	if err := os.WriteFile(xauth, appendXauth(nil, xauthFamilyLocal, hostname, "0", "MIT-MAGIC-COOKIE-1", cookie), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XAUTHORITY", xauth)
+
+	server := fakeX11{cookie: cookie, rules: "evdev\x00pc105\x00us,de\x00,nodeadkeys\x00grp:alt_shift_toggle\x00", xkb: true, group: 1}
+	kb, err := queryX11Keyboard(context.Background(), serveUnix(t, server.serve)+":0")
+	if err != nil {
+		t.Fatalf("queryX11Keyboard() returned an error: %v", err)
+	}
+	expected := x11Keyboard{rules: "evdev", model: "pc105", layout: "us,de", variant: ",nodeadkeys", options: "grp:alt_shift_toggle", group: 1}
+	if *kb != expected {
+		t.Errorf("queryX11Keyboard() = %+v, want %+v", *kb, expected)
+	}
+
+	// Without XKB, the active group is unknown
+	server.xkb = false
+	kb, err = queryX11Keyboard(context.Background(), serveUnix(t, server.serve)+":0")
+	if err != nil || kb.group != -1 || kb.layout != "us,de" {
+		t.Errorf("queryX11Keyboard() without XKB = %+v, %v", kb, err)
+	}
+
+	server.huge = true
+	if _, err := queryX11Keyboard(context.Background(), serveUnix(t, server.serve)+":0"); !errors.Is(err, ErrParse) {
+		t.Errorf("queryX11Keyboard() with an oversized reply error = %v, want %v", err, ErrParse)
+	}
+	server.huge = false
+
+	server.rules = ""
+	if _, err := queryX11Keyboard(context.Background(), serveUnix(t, server.serve)+":0"); !errors.Is(err, ErrParse) {
+		t.Errorf("queryX11Keyboard() without rules names error = %v, want %v", err, ErrParse)
+	}
+
+	server.cookie = []byte("fedcba9876543210")
+	_, err = queryX11Keyboard(context.Background(), serveUnix(t, server.serve)+":0")
+	if err == nil || !strings.Contains(err.Error(), "No protocol specified") {
+		t.Errorf("queryX11Keyboard() with a wrong cookie error = %v", err)
+	}
+
+	if _, err := queryX11Keyboard(context.Background(), filepath.Join(t.TempDir(), "missing")+":0"); !errors.Is(err, errNoDisplay) {
+		t.Errorf("queryX11Keyboard() without a server error = %v, want %v", err, errNoDisplay)
+	}
+}
+
+func TestQueryX11KeyboardAbstractSocket(t *testing.T) {
+	dir := t.TempDir()
+	l, err := net.Listen("unix", "@"+filepath.Join(dir, "X7"))
+	if err != nil {
+		t.Skipf("abstract sockets are not supported: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fakeX11{rules: "evdev\x00pc105\x00fr\x00\x00\x00", xkb: true}.serve(conn)
+			}()
+		}
+	}()
+
+	old := x11SocketDir
+	x11SocketDir = dir
+	t.Cleanup(func() { x11SocketDir = old })
+	t.Setenv("XAUTHORITY", filepath.Join(dir, "missing"))
+
+	kb, err := queryX11Keyboard(context.Background(), ":7")
+	if err != nil {
+		t.Fatalf("queryX11Keyboard() returned an error: %v", err)
+	}
+	if expected := []x11Layout{{"fr", ""}}; !reflect.DeepEqual(kb.layouts(), expected) {
+		t.Errorf("queryX11Keyboard() layouts = %v, want %v", kb.layouts(), expected)
+	}
+}
@@ -0,0 +1,249 @@
+package keyloc
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:generate go run gen_xkb.go
+
+// xkbRulesPaths lists where xkeyboard-config installs the evdev rules
+// registry, which describes every layout and variant it ships.
+var xkbRulesPaths = []string{
+	"/usr/share/X11/xkb/rules/evdev.xml",
+	"/usr/share/xkeyboard-config-2/rules/evdev.xml",
+}
+
+// x11Layout is an XKB layout with an optional variant, as configured in
+// the X11Layout and X11Variant lists.
+type x11Layout struct {
+	layout  string
+	variant string
+}
+
+// id returns the layout in XKB notation, e.g. "us" or "us(intl)".
+func (l x11Layout) id() string {
+	if l.variant == "" {
+		return l.layout
+	}
+	return l.layout + "(" + l.variant + ")"
+}
+
+// xkbLayout describes an XKB layout or variant in the rules registry.
+type xkbLayout struct {
+	lang   string // ISO 639 code of the main language
+	region string // ISO 3166 code, if the layout is meant for a single country
+	name   string
+}
+
+// tag returns the language tag of the layout.
+func (l xkbLayout) tag() string {
+	if l.region == "" {
+		return l.lang
+	}
+	return l.lang + "-" + l.region
+}
+
+// xkbRegistry is the rules registry of a system.
+type xkbRegistry struct {
+	layouts map[string]xkbLayout // Layouts and variants by ID
+	names   map[string]string    // Layout IDs by description
+}
+
+var (
+	xkbRegistriesMu sync.Mutex
+	xkbRegistries   = make(map[string]*xkbRegistry) // By root
+)
+
+// loadXKBRules returns the rules registry of the system at root, or of the
+// running system if root is "". It reads the evdev.xml installed there the
+// first time it is called for root, and uses the table generated from it
+// at build time if there is none.
+func loadXKBRules(root string) *xkbRegistry {
+	xkbRegistriesMu.Lock()
+	defer xkbRegistriesMu.Unlock()
+	if r, ok := xkbRegistries[root]; ok {
+		return r
+	}
+
+	r := &xkbRegistry{layouts: xkbLayouts}
+	for _, path := range rootPaths(root, xkbRulesPaths) {
+		if rules, err := readXKBRules(path); err == nil && len(rules) > 0 {
+			r.layouts = rules
+			break
+		}
+	}
+
+	// Descriptions are unique in practice; if not, prefer layouts over
+	// variants, then the first ID in sort order.
+	r.names = make(map[string]string, len(r.layouts))
+	for id, info := range r.layouts {
+		if prev, ok := r.names[info.name]; !ok || len(id) < len(prev) || (len(id) == len(prev) && id < prev) {
+			r.names[info.name] = id
+		}
+	}
+	xkbRegistries[root] = r
+	return r
+}
+
+// lookupXKBLayout returns the registry entry of a layout and variant in
+// the rules of root. An unknown variant falls back to its layout.
+func lookupXKBLayout(root string, l x11Layout) (xkbLayout, bool) {
+	rules := loadXKBRules(root).layouts
+	id := l.id()
+	info, ok := rules[id]
+	if !ok {
+		id = l.layout
+		if info, ok = rules[id]; !ok {
+			return xkbLayout{}, false
+		}
+	}
+	if o, ok := xkbOverrides[id]; ok {
+		info.lang, info.region = o.lang, o.region
+	}
+	return info, true
+}
+
+// xkbOverrides corrects the language and region of registry entries that
+// evdev.xml gets wrong, by layout ID: the Armenian layouts are listed for
+// Albania ("AL") instead of Armenia, and the Taiwanese layout under "fox",
+// a Formosan language code, instead of Chinese.
+var xkbOverrides = map[string]xkbLayout{
+	"am":               {lang: "hy", region: "AM"},
+	"am(eastern)":      {lang: "hy", region: "AM"},
+	"am(eastern-alt)":  {lang: "hy", region: "AM"},
+	"am(phonetic)":     {lang: "hy", region: "AM"},
+	"am(phonetic-alt)": {lang: "hy", region: "AM"},
+	"am(western)":      {lang: "hy", region: "AM"},
+	"tw":               {lang: "zh", region: "TW"},
+}
+
+// lookupXKBName returns the layout whose description is name, such as
+// "German (no dead keys)", in the rules of root, as Wayland compositors
+// report layouts by description only.
+func lookupXKBName(root, name string) (x11Layout, bool) {
+	id, ok := loadXKBRules(root).names[name]
+	if !ok {
+		return x11Layout{}, false
+	}
+	return splitX11Layouts(id, "")[0], true
+}
+
+// readXKBRules parses the rules registry at path.
+func readXKBRules(path string) (map[string]xkbLayout, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseXKBRules(f)
+}
+
+// xkbConfigItem is the configItem element of a layout or variant in an
+// xkeyboard-config rules registry.
+type xkbConfigItem struct {
+	Name        string   `xml:"name"`
+	Description string   `xml:"description"`
+	Countries   []string `xml:"countryList>iso3166Id"`
+	Languages   []string `xml:"languageList>iso639Id"`
+}
+
+// parseXKBRules reads the layouts and variants of an xkeyboard-config
+// rules registry such as evdev.xml. Layouts are keyed by name and variants
+// by "layout(variant)".
+func parseXKBRules(r io.Reader) (map[string]xkbLayout, error) {
+	var registry struct {
+		Layouts []struct {
+			ConfigItem xkbConfigItem   `xml:"configItem"`
+			Variants   []xkbConfigItem `xml:"variantList>variant>configItem"`
+		} `xml:"layoutList>layout"`
+	}
+	if err := xml.NewDecoder(r).Decode(&registry); err != nil {
+		return nil, parseError("xkb rules: %v", err)
+	}
+
+	rules := make(map[string]xkbLayout)
+	for _, layout := range registry.Layouts {
+		base := xkbLayoutFromItem(layout.ConfigItem)
+		rules[layout.ConfigItem.Name] = base
+		for _, item := range layout.Variants {
+			variant := xkbLayoutFromItem(item)
+			if variant.lang == "" {
+				// Variants without a language list are for the
+				// language and countries of their layout.
+				variant.lang, variant.region = base.lang, base.region
+			}
+			rules[x11Layout{layout.ConfigItem.Name, item.Name}.id()] = variant
+		}
+	}
+	return rules, nil
+}
+
+func xkbLayoutFromItem(item xkbConfigItem) xkbLayout {
+	l := xkbLayout{name: strings.TrimSpace(item.Description)}
+	if len(item.Languages) > 0 {
+		l.lang = strings.TrimSpace(item.Languages[0])
+	}
+	if len(item.Countries) == 1 {
+		l.region = strings.TrimSpace(item.Countries[0])
+	}
+	return l
+}
+
+// splitX11Layouts pairs up comma-separated X11 layout and variant lists,
+// such as "us,ara" and "intl,qwerty". Layouts may also carry their variant
+// inline, as in "us(intl)" or "us dvorak".
+func splitX11Layouts(layouts, variants string) []x11Layout {
+	variantList := strings.Split(variants, ",")
+
+	var result []x11Layout
+	for i, layout := range strings.Split(layouts, ",") {
+		layout = strings.TrimSpace(layout)
+		// localectl prints "n/a" when no X11 layout is configured
+		if layout == "" || layout == "n/a" {
+			continue
+		}
+
+		l := x11Layout{layout: layout}
+		if open := strings.IndexByte(layout, '('); open > 0 && strings.HasSuffix(layout, ")") {
+			l.layout, l.variant = layout[:open], layout[open+1:len(layout)-1]
+		} else if fields := strings.Fields(layout); len(fields) == 2 {
+			l.layout, l.variant = fields[0], fields[1]
+		}
+		if l.variant == "" && i < len(variantList) {
+			if v := strings.TrimSpace(variantList[i]); v != "n/a" {
+				l.variant = v
+			}
+		}
+		result = append(result, l)
+	}
+	return result
+}
+
+// x11LayoutSources returns X11 layouts as input sources, described by the
+// rules of root.
+func x11LayoutSources(backend, root string, layouts []x11Layout) []InputSource {
+	sources := make([]InputSource, 0, len(layouts))
+	for _, l := range layouts {
+		sources = append(sources, x11LayoutSource(backend, root, l))
+	}
+	return sources
+}
+
+// x11LayoutSource returns an X11 layout as an input source, described by
+// the rules of root.
+func x11LayoutSource(backend, root string, l x11Layout) InputSource {
+	// Without a registry entry, the layout name is often a language or
+	// country code already.
+	lang, name := l.layout, l.id()
+	if info, ok := lookupXKBLayout(root, l); ok {
+		if info.lang != "" {
+			lang = info.tag()
+		}
+		name = info.name
+	}
+	return newInputSource(l.id(), lang, name, backend)
+}
@@ -0,0 +1,586 @@
+// Code generated by gen_xkb.go; DO NOT EDIT.
+
+package keyloc
+
+// xkbLayouts holds the layouts and variants of xkeyboard-config's evdev
+// rules registry, for systems that do not have it installed.
+var xkbLayouts = map[string]xkbLayout{
+	"af":                             {"drs", "AF", "Dari"},
+	"af(fa-olpc)":                    {"drs", "AF", "Dari (Afghanistan, OLPC)"},
+	"af(ps)":                         {"pus", "", "Pashto"},
+	"af(ps-olpc)":                    {"pus", "", "Pashto (Afghanistan, OLPC)"},
+	"af(uz)":                         {"uzb", "", "Uzbek (Afghanistan)"},
+	"af(uz-olpc)":                    {"uzb", "", "Uzbek (Afghanistan, OLPC)"},
+	"al":                             {"sqi", "AL", "Albanian"},
+	"al(plisi)":                      {"sqi", "AL", "Albanian (Plisi)"},
+	"al(veqilharxhi)":                {"sqi", "AL", "Albanian (Veqilharxhi)"},
+	"am":                             {"hye", "AL", "Armenian"},
+	"am(eastern)":                    {"hye", "AL", "Armenian (eastern)"},
+	"am(eastern-alt)":                {"hye", "AL", "Armenian (alt. eastern)"},
+	"am(phonetic)":                   {"hye", "AL", "Armenian (phonetic)"},
+	"am(phonetic-alt)":               {"hye", "AL", "Armenian (alt. phonetic)"},
+	"am(western)":                    {"hye", "AL", "Armenian (western)"},
+	"ara":                            {"ara", "", "Arabic"},
+	"ara(azerty)":                    {"ara", "", "Arabic (AZERTY)"},
+	"ara(azerty_digits)":             {"ara", "", "Arabic (AZERTY, Eastern Arabic numerals)"},
+	"ara(buckwalter)":                {"ara", "", "Arabic (Buckwalter)"},
+	"ara(digits)":                    {"ara", "", "Arabic (Eastern Arabic numerals)"},
+	"ara(mac)":                       {"ara", "", "Arabic (Macintosh)"},
+	"ara(olpc)":                      {"ara", "", "Arabic (OLPC)"},
+	"ara(qwerty)":                    {"ara", "", "Arabic (QWERTY)"},
+	"ara(qwerty_digits)":             {"ara", "", "Arabic (QWERTY, Eastern Arabic numerals)"},
+	"at":                             {"deu", "AT", "German (Austria)"},
+	"at(mac)":                        {"deu", "AT", "German (Austria, Macintosh)"},
+	"at(nodeadkeys)":                 {"deu", "AT", "German (Austria, no dead keys)"},
+	"au":                             {"eng", "AU", "English (Australian)"},
+	"az":                             {"aze", "AZ", "Azerbaijani"},
+	"az(cyrillic)":                   {"aze", "AZ", "Azerbaijani (Cyrillic)"},
+	"ba":                             {"bos", "BA", "Bosnian"},
+	"ba(alternatequotes)":            {"bos", "BA", "Bosnian (with guillemets)"},
+	"ba(unicode)":                    {"bos", "BA", "Bosnian (with Bosnian digraphs)"},
+	"ba(unicodeus)":                  {"bos", "BA", "Bosnian (US, with Bosnian digraphs)"},
+	"ba(us)":                         {"bos", "BA", "Bosnian (US)"},
+	"bd":                             {"ben", "BD", "Bangla"},
+	"bd(probhat)":                    {"ben", "BD", "Bangla (Probhat)"},
+	"be":                             {"deu", "BE", "Belgian"},
+	"be(iso-alternate)":              {"deu", "BE", "Belgian (ISO, alt.)"},
+	"be(nodeadkeys)":                 {"deu", "BE", "Belgian (no dead keys)"},
+	"be(oss)":                        {"deu", "BE", "Belgian (alt.)"},
+	"be(oss_latin9)":                 {"deu", "BE", "Belgian (Latin-9 only, alt.)"},
+	"be(wang)":                       {"deu", "BE", "Belgian (Wang 724 AZERTY)"},
+	"bg":                             {"bul", "BG", "Bulgarian"},
+	"bg(bas_phonetic)":               {"bul", "BG", "Bulgarian (new phonetic)"},
+	"bg(bekl)":                       {"bul", "BG", "Bulgarian (enhanced)"},
+	"bg(phonetic)":                   {"bul", "BG", "Bulgarian (traditional phonetic)"},
+	"br":                             {"por", "BR", "Portuguese (Brazil)"},
+	"br(dvorak)":                     {"por", "BR", "Portuguese (Brazil, Dvorak)"},
+	"br(nativo)":                     {"por", "BR", "Portuguese (Brazil, Nativo)"},
+	"br(nativo-epo)":                 {"epo", "", "Esperanto (Brazil, Nativo)"},
+	"br(nativo-us)":                  {"por", "BR", "Portuguese (Brazil, Nativo for US keyboards)"},
+	"br(nodeadkeys)":                 {"por", "BR", "Portuguese (Brazil, no dead keys)"},
+	"br(thinkpad)":                   {"por", "BR", "Portuguese (Brazil, IBM/Lenovo ThinkPad)"},
+	"brai":                           {"", "", "Braille"},
+	"brai(left_hand)":                {"", "", "Braille (left-handed)"},
+	"brai(left_hand_invert)":         {"", "", "Braille (left-handed inverted thumb)"},
+	"brai(right_hand)":               {"", "", "Braille (right-handed)"},
+	"brai(right_hand_invert)":        {"", "", "Braille (right-handed inverted thumb)"},
+	"bt":                             {"dzo", "BT", "Dzongkha"},
+	"bw":                             {"tsn", "BW", "Tswana"},
+	"by":                             {"bel", "BY", "Belarusian"},
+	"by(intl)":                       {"bel", "BY", "Belarusian (intl.)"},
+	"by(latin)":                      {"bel", "BY", "Belarusian (Latin)"},
+	"by(legacy)":                     {"bel", "BY", "Belarusian (legacy)"},
+	"by(ru)":                         {"bel", "BY", "Russian (Belarus)"},
+	"ca":                             {"fra", "CA", "French (Canada)"},
+	"ca(eng)":                        {"eng", "", "English (Canada)"},
+	"ca(fr-dvorak)":                  {"fra", "CA", "French (Canada, Dvorak)"},
+	"ca(fr-legacy)":                  {"fra", "CA", "French (Canada, legacy)"},
+	"ca(ike)":                        {"iku", "", "Inuktitut"},
+	"ca(multi)":                      {"fra", "CA", "Canadian (intl., 1st part)"},
+	"ca(multi-2gr)":                  {"fra", "CA", "Canadian (intl., 2nd part)"},
+	"ca(multix)":                     {"fra", "CA", "Canadian (intl.)"},
+	"cd":                             {"fra", "CD", "French (Democratic Republic of the Congo)"},
+	"ch":                             {"deu", "CH", "German (Switzerland)"},
+	"ch(de_mac)":                     {"deu", "CH", "German (Switzerland, Macintosh)"},
+	"ch(de_nodeadkeys)":              {"deu", "CH", "German (Switzerland, no dead keys)"},
+	"ch(fr)":                         {"fra", "", "French (Switzerland)"},
+	"ch(fr_mac)":                     {"fra", "", "French (Switzerland, Macintosh)"},
+	"ch(fr_nodeadkeys)":              {"fra", "", "French (Switzerland, no dead keys)"},
+	"ch(legacy)":                     {"deu", "CH", "German (Switzerland, legacy)"},
+	"cm":                             {"eng", "CM", "English (Cameroon)"},
+	"cm(azerty)":                     {"fra", "", "Cameroon (AZERTY, intl.)"},
+	"cm(dvorak)":                     {"eng", "CM", "Cameroon (Dvorak, intl.)"},
+	"cm(french)":                     {"fra", "", "French (Cameroon)"},
+	"cm(mmuock)":                     {"eng", "CM", "Mmuock"},
+	"cm(qwerty)":                     {"eng", "", "Cameroon Multilingual (QWERTY, intl.)"},
+	"cn":                             {"zho", "CN", "Chinese"},
+	"cn(altgr-pinyin)":               {"zho", "", "Hanyu Pinyin Letters (with AltGr dead keys)"},
+	"cn(mon_manchu_galik)":           {"mnc", "", "Mongolian (Manchu Galik)"},
+	"cn(mon_todo_galik)":             {"mvf", "", "Mongolian (Todo Galik)"},
+	"cn(mon_trad)":                   {"mvf", "", "Mongolian (Bichig)"},
+	"cn(mon_trad_galik)":             {"mvf", "", "Mongolian (Galik)"},
+	"cn(mon_trad_manchu)":            {"mnc", "", "Mongolian (Manchu)"},
+	"cn(mon_trad_todo)":              {"mvf", "", "Mongolian (Todo)"},
+	"cn(mon_trad_xibe)":              {"sjo", "", "Mongolian (Xibe)"},
+	"cn(tib)":                        {"bod", "", "Tibetan"},
+	"cn(tib_asciinum)":               {"bod", "", "Tibetan (with ASCII numerals)"},
+	"cn(ug)":                         {"uig", "", "Uyghur"},
+	"custom":                         {"", "", "A user-defined custom Layout"},
+	"cz":                             {"ces", "CZ", "Czech"},
+	"cz(bksl)":                       {"ces", "CZ", "Czech (with <\\|> key)"},
+	"cz(dvorak-ucw)":                 {"ces", "CZ", "Czech (US, Dvorak, UCW support)"},
+	"cz(qwerty)":                     {"ces", "CZ", "Czech (QWERTY)"},
+	"cz(qwerty-mac)":                 {"ces", "CZ", "Czech (QWERTY, Macintosh)"},
+	"cz(qwerty_bksl)":                {"ces", "CZ", "Czech (QWERTY, extended backslash)"},
+	"cz(rus)":                        {"rus", "", "Russian (Czech, phonetic)"},
+	"cz(ucw)":                        {"ces", "CZ", "Czech (UCW, only accented letters)"},
+	"de":                             {"deu", "DE", "German"},
+	"de(T3)":                         {"deu", "DE", "German (T3)"},
+	"de(deadacute)":                  {"deu", "DE", "German (dead acute)"},
+	"de(deadgraveacute)":             {"deu", "DE", "German (dead grave acute)"},
+	"de(deadtilde)":                  {"deu", "DE", "German (dead tilde)"},
+	"de(dsb)":                        {"dsb", "", "Lower Sorbian"},
+	"de(dsb_qwertz)":                 {"dsb", "", "Lower Sorbian (QWERTZ)"},
+	"de(dvorak)":                     {"deu", "DE", "German (Dvorak)"},
+	"de(e1)":                         {"deu", "DE", "German (E1)"},
+	"de(e2)":                         {"deu", "DE", "German (E2)"},
+	"de(mac)":                        {"deu", "DE", "German (Macintosh)"},
+	"de(mac_nodeadkeys)":             {"deu", "DE", "German (Macintosh, no dead keys)"},
+	"de(neo)":                        {"deu", "DE", "German (Neo 2)"},
+	"de(nodeadkeys)":                 {"deu", "DE", "German (no dead keys)"},
+	"de(qwerty)":                     {"deu", "DE", "German (QWERTY)"},
+	"de(ro)":                         {"ron", "", "Romanian (Germany)"},
+	"de(ro_nodeadkeys)":              {"ron", "", "Romanian (Germany, no dead keys)"},
+	"de(ru)":                         {"rus", "", "Russian (Germany, phonetic)"},
+	"de(tr)":                         {"tur", "", "Turkish (Germany)"},
+	"de(us)":                         {"deu", "DE", "German (US)"},
+	"dk":                             {"dan", "DK", "Danish"},
+	"dk(dvorak)":                     {"dan", "DK", "Danish (Dvorak)"},
+	"dk(mac)":                        {"dan", "DK", "Danish (Macintosh)"},
+	"dk(mac_nodeadkeys)":             {"dan", "DK", "Danish (Macintosh, no dead keys)"},
+	"dk(nodeadkeys)":                 {"dan", "DK", "Danish (no dead keys)"},
+	"dk(winkeys)":                    {"dan", "DK", "Danish (Windows)"},
+	"dz":                             {"tzm", "DZ", "Berber (Algeria, Latin)"},
+	"dz(ar)":                         {"ara", "", "Arabic (Algeria)"},
+	"dz(azerty-deadkeys)":            {"kab", "", "Kabyle (AZERTY, with dead keys)"},
+	"dz(ber)":                        {"kab", "", "Berber (Algeria, Tifinagh)"},
+	"dz(qwerty-gb-deadkeys)":         {"kab", "", "Kabyle (QWERTY, UK, with dead keys)"},
+	"dz(qwerty-us-deadkeys)":         {"kab", "", "Kabyle (QWERTY, US, with dead keys)"},
+	"ee":                             {"est", "EE", "Estonian"},
+	"ee(dvorak)":                     {"est", "EE", "Estonian (Dvorak)"},
+	"ee(nodeadkeys)":                 {"est", "EE", "Estonian (no dead keys)"},
+	"ee(us)":                         {"est", "EE", "Estonian (US)"},
+	"epo":                            {"epo", "", "Esperanto"},
+	"epo(legacy)":                    {"epo", "", "Esperanto (legacy)"},
+	"es":                             {"spa", "ES", "Spanish"},
+	"es(ast)":                        {"ast", "", "Asturian (Spain, with bottom-dot H and L)"},
+	"es(cat)":                        {"cat", "", "Catalan (Spain, with middle-dot L)"},
+	"es(deadtilde)":                  {"spa", "ES", "Spanish (dead tilde)"},
+	"es(dvorak)":                     {"spa", "ES", "Spanish (Dvorak)"},
+	"es(mac)":                        {"spa", "ES", "Spanish (Macintosh)"},
+	"es(nodeadkeys)":                 {"spa", "ES", "Spanish (no dead keys)"},
+	"es(winkeys)":                    {"spa", "ES", "Spanish (Windows)"},
+	"et":                             {"amh", "ET", "Amharic"},
+	"fi":                             {"fin", "FI", "Finnish"},
+	"fi(classic)":                    {"fin", "FI", "Finnish (classic)"},
+	"fi(mac)":                        {"fin", "FI", "Finnish (Macintosh)"},
+	"fi(nodeadkeys)":                 {"fin", "FI", "Finnish (classic, no dead keys)"},
+	"fi(smi)":                        {"sme", "", "Northern Saami (Finland)"},
+	"fi(winkeys)":                    {"fin", "FI", "Finnish (Windows)"},
+	"fo":                             {"fao", "FO", "Faroese"},
+	"fo(nodeadkeys)":                 {"fao", "FO", "Faroese (no dead keys)"},
+	"fr":                             {"fra", "FR", "French"},
+	"fr(afnor)":                      {"fra", "FR", "French (AZERTY, AFNOR)"},
+	"fr(azerty)":                     {"fra", "FR", "French (AZERTY)"},
+	"fr(bepo)":                       {"fra", "FR", "French (BEPO)"},
+	"fr(bepo_afnor)":                 {"fra", "FR", "French (BEPO, AFNOR)"},
+	"fr(bepo_latin9)":                {"fra", "FR", "French (BEPO, Latin-9 only)"},
+	"fr(bre)":                        {"fra", "FR", "French (Breton)"},
+	"fr(dvorak)":                     {"fra", "FR", "French (Dvorak)"},
+	"fr(geo)":                        {"kat", "", "Georgian (France, AZERTY Tskapo)"},
+	"fr(latin9)":                     {"fra", "FR", "French (legacy, alt.)"},
+	"fr(latin9_nodeadkeys)":          {"fra", "FR", "French (legacy, alt., no dead keys)"},
+	"fr(mac)":                        {"fra", "FR", "French (Macintosh)"},
+	"fr(nodeadkeys)":                 {"fra", "FR", "French (no dead keys)"},
+	"fr(oci)":                        {"oci", "", "Occitan"},
+	"fr(oss)":                        {"fra", "FR", "French (alt.)"},
+	"fr(oss_latin9)":                 {"fra", "FR", "French (alt., Latin-9 only)"},
+	"fr(oss_nodeadkeys)":             {"fra", "FR", "French (alt., no dead keys)"},
+	"fr(us)":                         {"fra", "FR", "French (US)"},
+	"gb":                             {"eng", "GB", "English (UK)"},
+	"gb(colemak)":                    {"eng", "GB", "English (UK, Colemak)"},
+	"gb(colemak_dh)":                 {"eng", "GB", "English (UK, Colemak-DH)"},
+	"gb(dvorak)":                     {"eng", "GB", "English (UK, Dvorak)"},
+	"gb(dvorakukp)":                  {"eng", "GB", "English (UK, Dvorak, with UK punctuation)"},
+	"gb(extd)":                       {"eng", "GB", "English (UK, extended, Windows)"},
+	"gb(gla)":                        {"eng", "", "Scottish Gaelic"},
+	"gb(intl)":                       {"eng", "GB", "English (UK, intl., with dead keys)"},
+	"gb(mac)":                        {"eng", "GB", "English (UK, Macintosh)"},
+	"gb(mac_intl)":                   {"eng", "GB", "English (UK, Macintosh, intl.)"},
+	"gb(pl)":                         {"pol", "", "Polish (British keyboard)"},
+	"ge":                             {"kat", "GE", "Georgian"},
+	"ge(ergonomic)":                  {"kat", "GE", "Georgian (ergonomic)"},
+	"ge(mess)":                       {"kat", "GE", "Georgian (MESS)"},
+	"ge(os)":                         {"oss", "", "Ossetian (Georgia)"},
+	"ge(ru)":                         {"rus", "", "Russian (Georgia)"},
+	"gh":                             {"eng", "GH", "English (Ghana)"},
+	"gh(akan)":                       {"aka", "", "Akan"},
+	"gh(avn)":                        {"avn", "", "Avatime"},
+	"gh(ewe)":                        {"ewe", "", "Ewe"},
+	"gh(fula)":                       {"ful", "", "Fula"},
+	"gh(ga)":                         {"gaa", "", "Ga"},
+	"gh(generic)":                    {"eng", "GH", "English (Ghana, multilingual)"},
+	"gh(gillbt)":                     {"eng", "GH", "English (Ghana, GILLBT)"},
+	"gh(hausa)":                      {"hau", "", "Hausa (Ghana)"},
+	"gn":                             {"nqo", "GN", "N'Ko (AZERTY)"},
+	"gr":                             {"ell", "GR", "Greek"},
+	"gr(extended)":                   {"ell", "GR", "Greek (extended)"},
+	"gr(nodeadkeys)":                 {"ell", "GR", "Greek (no dead keys)"},
+	"gr(polytonic)":                  {"ell", "GR", "Greek (polytonic)"},
+	"gr(simple)":                     {"ell", "GR", "Greek (simple)"},
+	"hr":                             {"hrv", "HR", "Croatian"},
+	"hr(alternatequotes)":            {"hrv", "HR", "Croatian (with guillemets)"},
+	"hr(unicode)":                    {"hrv", "HR", "Croatian (with Croatian digraphs)"},
+	"hr(unicodeus)":                  {"hrv", "HR", "Croatian (US, with Croatian digraphs)"},
+	"hr(us)":                         {"hrv", "HR", "Croatian (US)"},
+	"hu":                             {"hun", "HU", "Hungarian"},
+	"hu(101_qwerty_comma_dead)":      {"hun", "HU", "Hungarian (QWERTY, 101-key, comma, dead keys)"},
+	"hu(101_qwerty_comma_nodead)":    {"hun", "HU", "Hungarian (QWERTY, 101-key, comma, no dead keys)"},
+	"hu(101_qwerty_dot_dead)":        {"hun", "HU", "Hungarian (QWERTY, 101-key, dot, dead keys)"},
+	"hu(101_qwerty_dot_nodead)":      {"hun", "HU", "Hungarian (QWERTY, 101-key, dot, no dead keys)"},
+	"hu(101_qwertz_comma_dead)":      {"hun", "HU", "Hungarian (QWERTZ, 101-key, comma, dead keys)"},
+	"hu(101_qwertz_comma_nodead)":    {"hun", "HU", "Hungarian (QWERTZ, 101-key, comma, no dead keys)"},
+	"hu(101_qwertz_dot_dead)":        {"hun", "HU", "Hungarian (QWERTZ, 101-key, dot, dead keys)"},
+	"hu(101_qwertz_dot_nodead)":      {"hun", "HU", "Hungarian (QWERTZ, 101-key, dot, no dead keys)"},
+	"hu(102_qwerty_comma_dead)":      {"hun", "HU", "Hungarian (QWERTY, 102-key, comma, dead keys)"},
+	"hu(102_qwerty_comma_nodead)":    {"hun", "HU", "Hungarian (QWERTY, 102-key, comma, no dead keys)"},
+	"hu(102_qwerty_dot_dead)":        {"hun", "HU", "Hungarian (QWERTY, 102-key, dot, dead keys)"},
+	"hu(102_qwerty_dot_nodead)":      {"hun", "HU", "Hungarian (QWERTY, 102-key, dot, no dead keys)"},
+	"hu(102_qwertz_comma_dead)":      {"hun", "HU", "Hungarian (QWERTZ, 102-key, comma, dead keys)"},
+	"hu(102_qwertz_comma_nodead)":    {"hun", "HU", "Hungarian (QWERTZ, 102-key, comma, no dead keys)"},
+	"hu(102_qwertz_dot_dead)":        {"hun", "HU", "Hungarian (QWERTZ, 102-key, dot, dead keys)"},
+	"hu(102_qwertz_dot_nodead)":      {"hun", "HU", "Hungarian (QWERTZ, 102-key, dot, no dead keys)"},
+	"hu(nodeadkeys)":                 {"hun", "HU", "Hungarian (no dead keys)"},
+	"hu(qwerty)":                     {"hun", "HU", "Hungarian (QWERTY)"},
+	"hu(standard)":                   {"hun", "HU", "Hungarian (standard)"},
+	"id":                             {"ind", "ID", "Indonesian (Latin)"},
+	"id(phonetic)":                   {"ind", "ID", "Indonesian (Arab Pegon, phonetic)"},
+	"id(phoneticx)":                  {"ind", "ID", "Indonesian (Arab Pegon, extended phonetic)"},
+	"ie":                             {"eng", "IE", "Irish"},
+	"ie(CloGaelach)":                 {"gle", "", "CloGaelach"},
+	"ie(UnicodeExpert)":              {"eng", "IE", "Irish (UnicodeExpert)"},
+	"ie(ogam)":                       {"sga", "", "Ogham"},
+	"ie(ogam_is434)":                 {"sga", "", "Ogham (IS434)"},
+	"il":                             {"heb", "IL", "Hebrew"},
+	"il(biblical)":                   {"heb", "IL", "Hebrew (Biblical, Tiro)"},
+	"il(lyx)":                        {"heb", "IL", "Hebrew (lyx)"},
+	"il(phonetic)":                   {"heb", "IL", "Hebrew (phonetic)"},
+	"in":                             {"hin", "IN", "Indian"},
+	"in(ben)":                        {"ben", "", "Bangla (India)"},
+	"in(ben_baishakhi)":              {"ben", "", "Bangla (India, Baishakhi)"},
+	"in(ben_bornona)":                {"ben", "", "Bangla (India, Bornona)"},
+	"in(ben_gitanjali)":              {"ben", "", "Bangla (India, Gitanjali)"},
+	"in(ben_inscript)":               {"ben", "", "Bangla (India, Baishakhi InScript)"},
+	"in(ben_probhat)":                {"ben", "", "Bangla (India, Probhat)"},
+	"in(bolnagri)":                   {"hin", "", "Hindi (Bolnagri)"},
+	"in(eeyek)":                      {"mni", "", "Manipuri (Eeyek)"},
+	"in(eng)":                        {"eng", "", "English (India, with rupee)"},
+	"in(guj)":                        {"guj", "", "Gujarati"},
+	"in(guru)":                       {"pan", "", "Punjabi (Gurmukhi)"},
+	"in(hin-kagapa)":                 {"hin", "", "Hindi (KaGaPa, phonetic)"},
+	"in(hin-wx)":                     {"hin", "", "Hindi (Wx)"},
+	"in(iipa)":                       {"eng", "", "Indic IPA"},
+	"in(jhelum)":                     {"pan", "", "Punjabi (Gurmukhi Jhelum)"},
+	"in(kan)":                        {"kan", "", "Kannada"},
+	"in(kan-kagapa)":                 {"kan", "", "Kannada (KaGaPa, phonetic)"},
+	"in(mal)":                        {"mal", "", "Malayalam"},
+	"in(mal_enhanced)":               {"mal", "", "Malayalam (enhanced InScript, with rupee)"},
+	"in(mal_lalitha)":                {"mal", "", "Malayalam (Lalitha)"},
+	"in(mar-kagapa)":                 {"mar", "", "Marathi (KaGaPa, phonetic)"},
+	"in(marathi)":                    {"mar", "", "Marathi (enhanced InScript)"},
+	"in(olck)":                       {"sat", "", "Ol Chiki"},
+	"in(ori)":                        {"ori", "", "Oriya"},
+	"in(ori-bolnagri)":               {"ori", "", "Oriya (Bolnagri)"},
+	"in(ori-wx)":                     {"ori", "", "Oriya (Wx)"},
+	"in(san-kagapa)":                 {"san", "", "Sanskrit (KaGaPa, phonetic)"},
+	"in(tam)":                        {"tam", "", "Tamil (InScript)"},
+	"in(tam_tamilnet)":               {"tam", "", "Tamil (TamilNet '99)"},
+	"in(tam_tamilnet_TAB)":           {"tam", "", "Tamil (TamilNet '99, TAB encoding)"},
+	"in(tam_tamilnet_TSCII)":         {"tam", "", "Tamil (TamilNet '99, TSCII encoding)"},
+	"in(tam_tamilnet_with_tam_nums)": {"tam", "", "Tamil (TamilNet '99 with Tamil numerals)"},
+	"in(tel)":                        {"tel", "", "Telugu"},
+	"in(tel-kagapa)":                 {"tel", "", "Telugu (KaGaPa, phonetic)"},
+	"in(tel-sarala)":                 {"tel", "", "Telugu (Sarala)"},
+	"in(urd-phonetic)":               {"urd", "", "Urdu (phonetic)"},
+	"in(urd-phonetic3)":              {"urd", "", "Urdu (alt. phonetic)"},
+	"in(urd-winkeys)":                {"urd", "", "Urdu (Windows)"},
+	"iq":                             {"ara", "IQ", "Iraqi"},
+	"iq(ku)":                         {"kur", "", "Kurdish (Iraq, Latin Q)"},
+	"iq(ku_alt)":                     {"kur", "", "Kurdish (Iraq, Latin Alt-Q)"},
+	"iq(ku_ara)":                     {"kur", "", "Kurdish (Iraq, Arabic-Latin)"},
+	"iq(ku_f)":                       {"kur", "", "Kurdish (Iraq, F)"},
+	"ir":                             {"fas", "IR", "Persian"},
+	"ir(ku)":                         {"kur", "", "Kurdish (Iran, Latin Q)"},
+	"ir(ku_alt)":                     {"kur", "", "Kurdish (Iran, Latin Alt-Q)"},
+	"ir(ku_ara)":                     {"kur", "", "Kurdish (Iran, Arabic-Latin)"},
+	"ir(ku_f)":                       {"kur", "", "Kurdish (Iran, F)"},
+	"ir(pes_keypad)":                 {"fas", "IR", "Persian (with Persian keypad)"},
+	"is":                             {"isl", "IS", "Icelandic"},
+	"is(dvorak)":                     {"isl", "IS", "Icelandic (Dvorak)"},
+	"is(mac)":                        {"isl", "IS", "Icelandic (Macintosh)"},
+	"is(mac_legacy)":                 {"isl", "IS", "Icelandic (Macintosh, legacy)"},
+	"it":                             {"ita", "IT", "Italian"},
+	"it(fur)":                        {"fur", "", "Friulian (Italy)"},
+	"it(geo)":                        {"kat", "", "Georgian (Italy)"},
+	"it(ibm)":                        {"ita", "IT", "Italian (IBM 142)"},
+	"it(intl)":                       {"deu", "", "Italian (intl., with dead keys)"},
+	"it(mac)":                        {"ita", "IT", "Italian (Macintosh)"},
+	"it(nodeadkeys)":                 {"ita", "IT", "Italian (no dead keys)"},
+	"it(scn)":                        {"ita", "", "Sicilian"},
+	"it(us)":                         {"ita", "IT", "Italian (US)"},
+	"it(winkeys)":                    {"ita", "IT", "Italian (Windows)"},
+	"jp":                             {"jpn", "JP", "Japanese"},
+	"jp(OADG109A)":                   {"jpn", "JP", "Japanese (OADG 109A)"},
+	"jp(dvorak)":                     {"jpn", "JP", "Japanese (Dvorak)"},
+	"jp(kana)":                       {"jpn", "JP", "Japanese (Kana)"},
+	"jp(kana86)":                     {"jpn", "JP", "Japanese (Kana 86)"},
+	"jp(mac)":                        {"jpn", "JP", "Japanese (Macintosh)"},
+	"jv":                             {"jav", "ID", "Indonesian (Javanese)"},
+	"ke":                             {"swa", "KE", "Swahili (Kenya)"},
+	"ke(kik)":                        {"kik", "", "Kikuyu"},
+	"kg":                             {"kir", "KG", "Kyrgyz"},
+	"kg(phonetic)":                   {"kir", "KG", "Kyrgyz (phonetic)"},
+	"kh":                             {"khm", "KH", "Khmer (Cambodia)"},
+	"kr":                             {"kor", "KR", "Korean"},
+	"kr(kr104)":                      {"kor", "KR", "Korean (101/104-key compatible)"},
+	"kz":                             {"kaz", "KZ", "Kazakh"},
+	"kz(ext)":                        {"kaz", "", "Kazakh (extended)"},
+	"kz(kazrus)":                     {"kaz", "", "Kazakh (with Russian)"},
+	"kz(latin)":                      {"kaz", "", "Kazakh (Latin)"},
+	"kz(ruskaz)":                     {"kaz", "", "Russian (Kazakhstan, with Kazakh)"},
+	"la":                             {"lao", "LA", "Lao"},
+	"la(stea)":                       {"lao", "", "Lao (STEA)"},
+	"latam":                          {"spa", "", "Spanish (Latin American)"},
+	"latam(colemak)":                 {"spa", "", "Spanish (Latin American, Colemak)"},
+	"latam(colemak-gaming)":          {"spa", "", "Spanish (Latin American, Colemak for gaming)"},
+	"latam(deadtilde)":               {"spa", "", "Spanish (Latin American, dead tilde)"},
+	"latam(dvorak)":                  {"spa", "", "Spanish (Latin American, Dvorak)"},
+	"latam(nodeadkeys)":              {"spa", "", "Spanish (Latin American, no dead keys)"},
+	"lk":                             {"sin", "LK", "Sinhala (phonetic)"},
+	"lk(tam_TAB)":                    {"tam", "", "Tamil (Sri Lanka, TamilNet '99, TAB encoding)"},
+	"lk(tam_unicode)":                {"tam", "", "Tamil (Sri Lanka, TamilNet '99)"},
+	"lk(us)":                         {"sin", "LK", "Sinhala (US)"},
+	"lt":                             {"lit", "LT", "Lithuanian"},
+	"lt(ibm)":                        {"lit", "LT", "Lithuanian (IBM LST 1205-92)"},
+	"lt(lekp)":                       {"lit", "LT", "Lithuanian (LEKP)"},
+	"lt(lekpa)":                      {"lit", "LT", "Lithuanian (LEKPa)"},
+	"lt(ratise)":                     {"lit", "LT", "Lithuanian (Ratise)"},
+	"lt(sgs)":                        {"sgs", "", "Samogitian"},
+	"lt(std)":                        {"lit", "LT", "Lithuanian (standard)"},
+	"lt(us)":                         {"lit", "LT", "Lithuanian (US)"},
+	"lv":                             {"lav", "LV", "Latvian"},
+	"lv(adapted)":                    {"lav", "LV", "Latvian (adapted)"},
+	"lv(apostrophe)":                 {"lav", "LV", "Latvian (apostrophe)"},
+	"lv(ergonomic)":                  {"lav", "LV", "Latvian (ergonomic, ŪGJRMV)"},
+	"lv(fkey)":                       {"lav", "LV", "Latvian (F)"},
+	"lv(modern)":                     {"lav", "LV", "Latvian (modern)"},
+	"lv(tilde)":                      {"lav", "LV", "Latvian (tilde)"},
+	"ma":                             {"ary", "MA", "Arabic (Morocco)"},
+	"ma(french)":                     {"fra", "", "French (Morocco)"},
+	"ma(rif)":                        {"rif", "", "Tarifit"},
+	"ma(tifinagh)":                   {"ber", "", "Berber (Morocco, Tifinagh)"},
+	"ma(tifinagh-alt)":               {"ber", "", "Berber (Morocco, Tifinagh alt.)"},
+	"ma(tifinagh-alt-phonetic)":      {"ber", "", "Berber (Morocco, Tifinagh phonetic, alt.)"},
+	"ma(tifinagh-extended)":          {"ber", "", "Berber (Morocco, Tifinagh extended)"},
+	"ma(tifinagh-extended-phonetic)": {"ber", "", "Berber (Morocco, Tifinagh extended phonetic)"},
+	"ma(tifinagh-phonetic)":          {"ber", "", "Berber (Morocco, Tifinagh phonetic)"},
+	"mao":                            {"mri", "NZ", "Maori"},
+	"md":                             {"ron", "MD", "Moldavian"},
+	"md(gag)":                        {"gag", "", "Moldavian (Gagauz)"},
+	"me":                             {"srp", "ME", "Montenegrin"},
+	"me(cyrillic)":                   {"srp", "ME", "Montenegrin (Cyrillic)"},
+	"me(cyrillicalternatequotes)":    {"srp", "ME", "Montenegrin (Cyrillic, with guillemets)"},
+	"me(cyrillicyz)":                 {"srp", "ME", "Montenegrin (Cyrillic, ZE and ZHE swapped)"},
+	"me(latinalternatequotes)":       {"srp", "ME", "Montenegrin (Latin, with guillemets)"},
+	"me(latinunicode)":               {"srp", "ME", "Montenegrin (Latin, Unicode)"},
+	"me(latinunicodeyz)":             {"srp", "ME", "Montenegrin (Latin, Unicode, QWERTY)"},
+	"me(latinyz)":                    {"srp", "ME", "Montenegrin (Latin, QWERTY)"},
+	"mk":                             {"mkd", "MK", "Macedonian"},
+	"mk(nodeadkeys)":                 {"mkd", "MK", "Macedonian (no dead keys)"},
+	"ml":                             {"bam", "ML", "Bambara"},
+	"ml(fr-oss)":                     {"fra", "", "French (Mali, alt.)"},
+	"ml(us-intl)":                    {"eng", "", "English (Mali, US, intl.)"},
+	"ml(us-mac)":                     {"eng", "", "English (Mali, US, Macintosh)"},
+	"mm":                             {"mya", "MM", "Burmese"},
+	"mm(mnw)":                        {"mnw", "", "Mon"},
+	"mm(mnw-a1)":                     {"mnw", "", "Mon (A1)"},
+	"mm(shn)":                        {"shn", "", "Shan"},
+	"mm(zawgyi)":                     {"mya", "", "Burmese Zawgyi"},
+	"mm(zgt)":                        {"shn", "", "Shan (Zawgyi Tai)"},
+	"mn":                             {"mon", "MN", "Mongolian"},
+	"mt":                             {"mlt", "MT", "Maltese"},
+	"mt(alt-gb)":                     {"mlt", "MT", "Maltese (UK, with AltGr overrides)"},
+	"mt(alt-us)":                     {"mlt", "MT", "Maltese (US, with AltGr overrides)"},
+	"mt(us)":                         {"mlt", "MT", "Maltese (US)"},
+	"mv":                             {"div", "MV", "Dhivehi"},
+	"my":                             {"ind", "MY", "Malay (Jawi, Arabic Keyboard)"},
+	"my(phonetic)":                   {"ind", "MY", "Malay (Jawi, phonetic)"},
+	"ng":                             {"eng", "NG", "English (Nigeria)"},
+	"ng(hausa)":                      {"hau", "", "Hausa (Nigeria)"},
+	"ng(igbo)":                       {"ibo", "", "Igbo"},
+	"ng(yoruba)":                     {"yor", "", "Yoruba"},
+	"nl":                             {"nld", "NL", "Dutch"},
+	"nl(mac)":                        {"nld", "NL", "Dutch (Macintosh)"},
+	"nl(std)":                        {"nld", "NL", "Dutch (standard)"},
+	"nl(us)":                         {"nld", "NL", "Dutch (US)"},
+	"no":                             {"nor", "NO", "Norwegian"},
+	"no(colemak)":                    {"nor", "NO", "Norwegian (Colemak)"},
+	"no(dvorak)":                     {"nor", "NO", "Norwegian (Dvorak)"},
+	"no(mac)":                        {"nor", "NO", "Norwegian (Macintosh)"},
+	"no(mac_nodeadkeys)":             {"nor", "NO", "Norwegian (Macintosh, no dead keys)"},
+	"no(nodeadkeys)":                 {"nor", "NO", "Norwegian (no dead keys)"},
+	"no(smi)":                        {"sme", "", "Northern Saami (Norway)"},
+	"no(smi_nodeadkeys)":             {"sme", "", "Northern Saami (Norway, no dead keys)"},
+	"no(winkeys)":                    {"nor", "NO", "Norwegian (Windows)"},
+	"np":                             {"nep", "NP", "Nepali"},
+	"ph":                             {"eng", "PH", "Filipino"},
+	"ph(capewell-dvorak)":            {"eng", "PH", "Filipino (Capewell-Dvorak, Latin)"},
+	"ph(capewell-dvorak-bay)":        {"bik", "", "Filipino (Capewell-Dvorak, Baybayin)"},
+	"ph(capewell-qwerf2k6)":          {"eng", "PH", "Filipino (Capewell-QWERF 2006, Latin)"},
+	"ph(capewell-qwerf2k6-bay)":      {"bik", "", "Filipino (Capewell-QWERF 2006, Baybayin)"},
+	"ph(colemak)":                    {"eng", "PH", "Filipino (Colemak, Latin)"},
+	"ph(colemak-bay)":                {"bik", "", "Filipino (Colemak, Baybayin)"},
+	"ph(dvorak)":                     {"eng", "PH", "Filipino (Dvorak, Latin)"},
+	"ph(dvorak-bay)":                 {"bik", "", "Filipino (Dvorak, Baybayin)"},
+	"ph(qwerty-bay)":                 {"bik", "", "Filipino (QWERTY, Baybayin)"},
+	"pk":                             {"urd", "PK", "Urdu (Pakistan)"},
+	"pk(ara)":                        {"ara", "", "Arabic (Pakistan)"},
+	"pk(snd)":                        {"snd", "", "Sindhi"},
+	"pk(urd-crulp)":                  {"urd", "PK", "Urdu (Pakistan, CRULP)"},
+	"pk(urd-nla)":                    {"urd", "PK", "Urdu (Pakistan, NLA)"},
+	"pl":                             {"pol", "PL", "Polish"},
+	"pl(csb)":                        {"csb", "", "Kashubian"},
+	"pl(dvorak)":                     {"pol", "PL", "Polish (Dvorak)"},
+	"pl(dvorak_altquotes)":           {"pol", "PL", "Polish (Dvorak, with Polish quotes on key 1)"},
+	"pl(dvorak_quotes)":              {"pol", "PL", "Polish (Dvorak, with Polish quotes on quotemark key)"},
+	"pl(dvp)":                        {"pol", "PL", "Polish (programmer Dvorak)"},
+	"pl(legacy)":                     {"pol", "PL", "Polish (legacy)"},
+	"pl(qwertz)":                     {"pol", "PL", "Polish (QWERTZ)"},
+	"pl(ru_phonetic_dvorak)":         {"rus", "", "Russian (Poland, phonetic Dvorak)"},
+	"pl(szl)":                        {"szl", "", "Silesian"},
+	"pt":                             {"por", "PT", "Portuguese"},
+	"pt(mac)":                        {"por", "PT", "Portuguese (Macintosh)"},
+	"pt(mac_nodeadkeys)":             {"por", "PT", "Portuguese (Macintosh, no dead keys)"},
+	"pt(nativo)":                     {"por", "PT", "Portuguese (Nativo)"},
+	"pt(nativo-epo)":                 {"epo", "", "Esperanto (Portugal, Nativo)"},
+	"pt(nativo-us)":                  {"por", "PT", "Portuguese (Nativo for US keyboards)"},
+	"pt(nodeadkeys)":                 {"por", "PT", "Portuguese (no dead keys)"},
+	"ro":                             {"ron", "RO", "Romanian"},
+	"ro(std)":                        {"ron", "RO", "Romanian (standard)"},
+	"ro(winkeys)":                    {"ron", "RO", "Romanian (Windows)"},
+	"rs":                             {"srp", "RS", "Serbian"},
+	"rs(alternatequotes)":            {"srp", "RS", "Serbian (Cyrillic, with guillemets)"},
+	"rs(latin)":                      {"srp", "RS", "Serbian (Latin)"},
+	"rs(latinalternatequotes)":       {"srp", "RS", "Serbian (Latin, with guillemets)"},
+	"rs(latinunicode)":               {"srp", "RS", "Serbian (Latin, Unicode)"},
+	"rs(latinunicodeyz)":             {"srp", "RS", "Serbian (Latin, Unicode, QWERTY)"},
+	"rs(latinyz)":                    {"srp", "RS", "Serbian (Latin, QWERTY)"},
+	"rs(rue)":                        {"rue", "", "Pannonian Rusyn"},
+	"rs(yz)":                         {"srp", "RS", "Serbian (Cyrillic, ZE and ZHE swapped)"},
+	"ru":                             {"rus", "RU", "Russian"},
+	"ru(bak)":                        {"bak", "", "Bashkirian"},
+	"ru(chm)":                        {"chm", "", "Mari"},
+	"ru(cv)":                         {"chv", "", "Chuvash"},
+	"ru(cv_latin)":                   {"chv", "", "Chuvash (Latin)"},
+	"ru(dos)":                        {"rus", "RU", "Russian (DOS)"},
+	"ru(kom)":                        {"kom", "", "Komi"},
+	"ru(legacy)":                     {"rus", "RU", "Russian (legacy)"},
+	"ru(mac)":                        {"rus", "RU", "Russian (Macintosh)"},
+	"ru(os_legacy)":                  {"oss", "", "Ossetian (legacy)"},
+	"ru(os_winkeys)":                 {"oss", "", "Ossetian (Windows)"},
+	"ru(phonetic)":                   {"rus", "RU", "Russian (phonetic)"},
+	"ru(phonetic_YAZHERTY)":          {"rus", "RU", "Russian (phonetic, YAZHERTY)"},
+	"ru(phonetic_azerty)":            {"rus", "RU", "Russian (phonetic, AZERTY)"},
+	"ru(phonetic_dvorak)":            {"rus", "RU", "Russian (phonetic, Dvorak)"},
+	"ru(phonetic_fr)":                {"rus", "RU", "Russian (phonetic, French)"},
+	"ru(phonetic_winkeys)":           {"rus", "RU", "Russian (phonetic, Windows)"},
+	"ru(sah)":                        {"sah", "", "Yakut"},
+	"ru(srp)":                        {"rus", "", "Serbian (Russia)"},
+	"ru(tt)":                         {"tat", "", "Tatar"},
+	"ru(typewriter)":                 {"rus", "RU", "Russian (typewriter)"},
+	"ru(typewriter-legacy)":          {"rus", "RU", "Russian (typewriter, legacy)"},
+	"ru(udm)":                        {"udm", "", "Udmurt"},
+	"ru(xal)":                        {"xal", "", "Kalmyk"},
+	"se":                             {"swe", "SE", "Swedish"},
+	"se(dvorak)":                     {"swe", "SE", "Swedish (Dvorak)"},
+	"se(mac)":                        {"swe", "SE", "Swedish (Macintosh)"},
+	"se(nodeadkeys)":                 {"swe", "SE", "Swedish (no dead keys)"},
+	"se(rus)":                        {"rus", "", "Russian (Sweden, phonetic)"},
+	"se(rus_nodeadkeys)":             {"rus", "", "Russian (Sweden, phonetic, no dead keys)"},
+	"se(smi)":                        {"sme", "", "Northern Saami (Sweden)"},
+	"se(svdvorak)":                   {"swe", "SE", "Swedish (Svdvorak)"},
+	"se(swl)":                        {"swl", "", "Swedish Sign Language"},
+	"se(us)":                         {"swe", "SE", "Swedish (US)"},
+	"se(us_dvorak)":                  {"swe", "SE", "Swedish (Dvorak, intl.)"},
+	"si":                             {"slv", "SI", "Slovenian"},
+	"si(alternatequotes)":            {"slv", "SI", "Slovenian (with guillemets)"},
+	"si(us)":                         {"slv", "SI", "Slovenian (US)"},
+	"sk":                             {"slk", "SK", "Slovak"},
+	"sk(bksl)":                       {"slk", "SK", "Slovak (extended backslash)"},
+	"sk(qwerty)":                     {"slk", "SK", "Slovak (QWERTY)"},
+	"sk(qwerty_bksl)":                {"slk", "SK", "Slovak (QWERTY, extended backslash)"},
+	"sn":                             {"wol", "SN", "Wolof"},
+	"sy":                             {"syr", "SY", "Arabic (Syria)"},
+	"sy(ku)":                         {"kur", "", "Kurdish (Syria, Latin Q)"},
+	"sy(ku_alt)":                     {"kur", "", "Kurdish (Syria, Latin Alt-Q)"},
+	"sy(ku_f)":                       {"kur", "", "Kurdish (Syria, F)"},
+	"sy(syc)":                        {"syr", "SY", "Syriac"},
+	"sy(syc_phonetic)":               {"syr", "SY", "Syriac (phonetic)"},
+	"tg":                             {"fra", "TG", "French (Togo)"},
+	"th":                             {"tha", "TH", "Thai"},
+	"th(pat)":                        {"tha", "TH", "Thai (Pattachote)"},
+	"th(tis)":                        {"tha", "TH", "Thai (TIS-820.2538)"},
+	"tj":                             {"tgk", "TJ", "Tajik"},
+	"tj(legacy)":                     {"tgk", "TJ", "Tajik (legacy)"},
+	"tm":                             {"tuk", "TM", "Turkmen"},
+	"tm(alt)":                        {"tuk", "TM", "Turkmen (Alt-Q)"},
+	"tr":                             {"tur", "TR", "Turkish"},
+	"tr(alt)":                        {"tur", "TR", "Turkish (Alt-Q)"},
+	"tr(f)":                          {"tur", "TR", "Turkish (F)"},
+	"tr(intl)":                       {"tur", "TR", "Turkish (intl., with dead keys)"},
+	"tr(ku)":                         {"kur", "", "Kurdish (Turkey, Latin Q)"},
+	"tr(ku_alt)":                     {"kur", "", "Kurdish (Turkey, Latin Alt-Q)"},
+	"tr(ku_f)":                       {"kur", "", "Kurdish (Turkey, F)"},
+	"tr(ot)":                         {"tur", "TR", "Ottoman (Q)"},
+	"tr(otf)":                        {"tur", "TR", "Ottoman (F)"},
+	"tr(otk)":                        {"tur", "TR", "Old Turkic"},
+	"tr(otkf)":                       {"tur", "TR", "Old Turkic (F)"},
+	"tw":                             {"fox", "TW", "Taiwanese"},
+	"tw(indigenous)":                 {"ami", "", "Taiwanese (indigenous)"},
+	"tw(saisiyat)":                   {"xsy", "", "Saisiyat (Taiwan)"},
+	"tz":                             {"swa", "TZ", "Swahili (Tanzania)"},
+	"ua":                             {"ukr", "UA", "Ukrainian"},
+	"ua(crh)":                        {"crh", "", "Crimean Tatar (Turkish Q)"},
+	"ua(crh_alt)":                    {"crh", "", "Crimean Tatar (Turkish Alt-Q)"},
+	"ua(crh_f)":                      {"crh", "", "Crimean Tatar (Turkish F)"},
+	"ua(homophonic)":                 {"ukr", "UA", "Ukrainian (homophonic)"},
+	"ua(legacy)":                     {"ukr", "UA", "Ukrainian (legacy)"},
+	"ua(macOS)":                      {"ukr", "UA", "Ukrainian (macOS)"},
+	"ua(phonetic)":                   {"ukr", "UA", "Ukrainian (phonetic)"},
+	"ua(rstu)":                       {"ukr", "UA", "Ukrainian (standard RSTU)"},
+	"ua(rstu_ru)":                    {"ukr", "UA", "Russian (Ukraine, standard RSTU)"},
+	"ua(typewriter)":                 {"ukr", "UA", "Ukrainian (typewriter)"},
+	"ua(winkeys)":                    {"ukr", "UA", "Ukrainian (Windows)"},
+	"us":                             {"eng", "US", "English (US)"},
+	"us(alt-intl)":                   {"eng", "US", "English (US, alt. intl.)"},
+	"us(altgr-intl)":                 {"eng", "", "English (intl., with AltGr dead keys)"},
+	"us(chr)":                        {"chr", "", "Cherokee"},
+	"us(colemak)":                    {"eng", "US", "English (Colemak)"},
+	"us(colemak_dh)":                 {"eng", "US", "English (Colemak-DH)"},
+	"us(colemak_dh_iso)":             {"eng", "US", "English (Colemak-DH ISO)"},
+	"us(dvorak)":                     {"eng", "US", "English (Dvorak)"},
+	"us(dvorak-alt-intl)":            {"eng", "US", "English (Dvorak, alt. intl.)"},
+	"us(dvorak-classic)":             {"eng", "US", "English (classic Dvorak)"},
+	"us(dvorak-intl)":                {"eng", "US", "English (Dvorak, intl., with dead keys)"},
+	"us(dvorak-l)":                   {"eng", "US", "English (Dvorak, left-handed)"},
+	"us(dvorak-mac)":                 {"eng", "US", "English (Dvorak, Macintosh)"},
+	"us(dvorak-r)":                   {"eng", "US", "English (Dvorak, right-handed)"},
+	"us(dvp)":                        {"eng", "US", "English (programmer Dvorak)"},
+	"us(euro)":                       {"eng", "US", "English (US, euro on 5)"},
+	"us(haw)":                        {"haw", "", "Hawaiian"},
+	"us(hbs)":                        {"eng", "", "Serbo-Croatian (US)"},
+	"us(intl)":                       {"eng", "US", "English (US, intl., with dead keys)"},
+	"us(mac)":                        {"eng", "US", "English (Macintosh)"},
+	"us(norman)":                     {"eng", "US", "English (Norman)"},
+	"us(olpc2)":                      {"eng", "US", "English (the divide/multiply toggle the layout)"},
+	"us(rus)":                        {"rus", "", "Russian (US, phonetic)"},
+	"us(symbolic)":                   {"eng", "US", "English (US, Symbolic)"},
+	"us(workman)":                    {"eng", "US", "English (Workman)"},
+	"us(workman-intl)":               {"eng", "US", "English (Workman, intl., with dead keys)"},
+	"uz":                             {"uzb", "UZ", "Uzbek"},
+	"uz(latin)":                      {"uzb", "UZ", "Uzbek (Latin)"},
+	"vn":                             {"vie", "VN", "Vietnamese"},
+	"vn(fr)":                         {"vie", "VN", "Vietnamese (French)"},
+	"vn(us)":                         {"vie", "VN", "Vietnamese (US)"},
+	"za":                             {"eng", "ZA", "English (South Africa)"},
+}
@@ -0,0 +1,109 @@
+package keyloc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testEvdevXML = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE xkbConfigRegistry SYSTEM "xkb.dtd">
+<xkbConfigRegistry version="1.1">
+  <modelList>
+    <model><configItem><name>pc105</name><description>Generic 105-key PC</description></configItem></model>
+  </modelList>
+  <layoutList>
+    <layout>
+      <configItem>
+        <name>us</name>
+        <shortDescription>en</shortDescription>
+        <description>English (US)</description>
+        <countryList><iso3166Id>US</iso3166Id></countryList>
+        <languageList><iso639Id>eng</iso639Id></languageList>
+      </configItem>
+      <variantList>
+        <variant><configItem><name>intl</name><description>English (US, intl., with dead keys)</description></configItem></variant>
+        <variant><configItem><name>chr</name><description>Cherokee</description><languageList><iso639Id>chr</iso639Id></languageList></configItem></variant>
+      </variantList>
+    </layout>
+    <layout>
+      <configItem>
+        <name>ara</name>
+        <description>Arabic</description>
+        <countryList><iso3166Id>EG</iso3166Id><iso3166Id>JO</iso3166Id></countryList>
+        <languageList><iso639Id>ara</iso639Id></languageList>
+      </configItem>
+    </layout>
+  </layoutList>
+</xkbConfigRegistry>
+`
+
+func TestParseXKBRules(t *testing.T) {
+	rules, err := parseXKBRules(strings.NewReader(testEvdevXML))
+	if err != nil {
+		t.Fatalf("parseXKBRules() returned an error: %v", err)
+	}
+
+	expected := map[string]xkbLayout{
+		"us":       {"eng", "US", "English (US)"},
+		"us(intl)": {"eng", "US", "English (US, intl., with dead keys)"},
+		"us(chr)":  {"chr", "", "Cherokee"},
+		"ara":      {"ara", "", "Arabic"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("parseXKBRules() = %v, want %v", rules, expected)
+	}
+}
+
+func TestSplitX11Layouts(t *testing.T) {
+	tests := []struct {
+		layouts, variants string
+		expected          []x11Layout
+	}{
+		{"us,kr", "", []x11Layout{{"us", ""}, {"kr", ""}}},
+		{"us,ara", "intl,qwerty", []x11Layout{{"us", "intl"}, {"ara", "qwerty"}}},
+		{"de,us", ",dvorak", []x11Layout{{"de", ""}, {"us", "dvorak"}}},
+		{"us(intl),ara", ",qwerty", []x11Layout{{"us", "intl"}, {"ara", "qwerty"}}},
+		{"us dvorak", "", []x11Layout{{"us", "dvorak"}}},
+		{"n/a", "n/a", nil},
+	}
+
+	for _, test := range tests {
+		if got := splitX11Layouts(test.layouts, test.variants); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("splitX11Layouts(%q, %q) = %v, want %v", test.layouts, test.variants, got, test.expected)
+		}
+	}
+}
+
+func TestX11LayoutSources(t *testing.T) {
+	sources := x11LayoutSources("test", "", []x11Layout{
+		{"us", "intl"},
+		{"ara", "qwerty"},
+		{"ca", ""},
+		{"de", "no-such-variant"},
+		{"zz", ""},
+		{"am", "phonetic"},
+		{"tw", ""},
+		{"tw", "indigenous"},
+	})
+
+	expected := []struct{ id, lang string }{
+		{"us(intl)", "en-US"},
+		{"ara(qwerty)", "ar"},
+		{"ca", "fr-CA"},
+		{"de(no-such-variant)", "de-DE"},
+		{"zz", "zz"},
+		// Corrected by xkbOverrides
+		{"am(phonetic)", "hy-AM"},
+		{"tw", "zh-TW"},
+		{"tw(indigenous)", "ami"},
+	}
+	if len(sources) != len(expected) {
+		t.Fatalf("x11LayoutSources() returned %d sources, want %d", len(sources), len(expected))
+	}
+	for i, want := range expected {
+		if sources[i].ID != want.id || sources[i].Language != want.lang {
+			t.Errorf("x11LayoutSources()[%d] = %s/%s, want %s/%s", i, sources[i].ID, sources[i].Language, want.id, want.lang)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package keyloc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// xorgConfigPath is the main X server configuration file.
+const xorgConfigPath = "/etc/X11/xorg.conf"
+
+// xorgConfigDirs lists the X server configuration directories in the order
+// the server reads them. systemd-localed writes 00-keyboard.conf to the first.
+var xorgConfigDirs = []string{
+	"/etc/X11/xorg.conf.d",
+	"/usr/share/X11/xorg.conf.d",
+}
+
+// xorgKeyboard is the XKB configuration that the X server applies to keyboards.
+type xorgKeyboard struct {
+	layout  string
+	variant string
+	model   string
+	options string
+}
+
+// xorgSection is a Section of an xorg.conf file. Option names are
+// normalized with xorgOptionName.
+type xorgSection struct {
+	name    string
+	entries map[string]string // Keyword entries, such as MatchIsKeyboard, by lower-case keyword
+	options map[string]string
+}
+
+// xorgConfigFiles returns the X server configuration files below root, in
+// the order they are read.
+func xorgConfigFiles(root string) []string {
+	files := []string{rootPath(root, xorgConfigPath)}
+	for _, dir := range rootPaths(root, xorgConfigDirs) {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.conf"))
+		sort.Strings(paths)
+		files = append(files, paths...)
+	}
+	return files
+}
+
+// readXorgKeyboard reads the keyboard configuration from the X server
+// configuration files. Files that do not exist are skipped. It reports
+// false if none of them configures a keyboard layout.
+func readXorgKeyboard(files []string) (xorgKeyboard, bool, error) {
+	var kb xorgKeyboard
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return xorgKeyboard{}, false, err
+		}
+		sections, err := parseXorgConf(data)
+		if err != nil {
+			return xorgKeyboard{}, false, err
+		}
+		applyXorgKeyboard(&kb, sections)
+	}
+	return kb, kb.layout != "", nil
+}
+
+// applyXorgKeyboard applies the XKB options of the InputClass sections
+// that match all keyboards to kb. As in the X server, options of later
+// sections override those of earlier ones. Sections that configure
+// particular devices, such as InputDevice sections and classes with other
+// Match entries, are skipped.
+func applyXorgKeyboard(kb *xorgKeyboard, sections []xorgSection) {
+	for _, s := range sections {
+		if !strings.EqualFold(s.name, "InputClass") || !xorgMatchesKeyboards(s) {
+			continue
+		}
+		for name, field := range map[string]*string{
+			"xkblayout":  &kb.layout,
+			"xkbvariant": &kb.variant,
+			"xkbmodel":   &kb.model,
+			"xkboptions": &kb.options,
+		} {
+			if v, ok := s.options[name]; ok {
+				*field = v
+			}
+		}
+	}
+}
+
+// xorgMatchesKeyboards reports whether an InputClass section applies to
+// all keyboards: its only Match entry is MatchIsKeyboard, or it has none,
+// in which case it applies to all devices.
+func xorgMatchesKeyboards(s xorgSection) bool {
+	for keyword, v := range s.entries {
+		switch {
+		case keyword == "matchiskeyboard":
+			if !xorgBool(v) {
+				return false
+			}
+		case strings.HasPrefix(keyword, "match"):
+			return false
+		}
+	}
+	return true
+}
+
+// parseXorgConf parses the sections of an xorg.conf file, such as
+//
+//	Section "InputClass"
+//	        Identifier "system-keyboard"
+//	        MatchIsKeyboard "on"
+//	        Option "XkbLayout" "us,ru"
+//	        Option "XkbVariant" ",winkeys"
+//	EndSection
+//
+// Subsections are skipped.
+func parseXorgConf(data []byte) ([]xorgSection, error) {
+	var sections []xorgSection
+	var current *xorgSection
+	depth := 0
+	for n, line := range strings.Split(string(data), "\n") {
+		tokens, err := tokenizeXorgLine(line)
+		if err != nil {
+			return nil, parseError("xorg.conf line %d: %v", n+1, err)
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+
+		keyword := strings.ToLower(tokens[0])
+		switch {
+		case keyword == "section":
+			if current != nil {
+				return nil, parseError("xorg.conf line %d: nested Section", n+1)
+			}
+			if len(tokens) < 2 {
+				return nil, parseError("xorg.conf line %d: Section without a name", n+1)
+			}
+			current = &xorgSection{name: tokens[1], entries: make(map[string]string), options: make(map[string]string)}
+		case keyword == "endsection":
+			if current == nil || depth > 0 {
+				return nil, parseError("xorg.conf line %d: unexpected EndSection", n+1)
+			}
+			sections = append(sections, *current)
+			current = nil
+		case keyword == "subsection":
+			depth++
+		case keyword == "endsubsection":
+			depth--
+		case current == nil:
+			return nil, parseError("xorg.conf line %d: %s outside of a Section", n+1, tokens[0])
+		case depth > 0:
+		case keyword == "option":
+			if len(tokens) < 2 {
+				return nil, parseError("xorg.conf line %d: Option without a name", n+1)
+			}
+			value := ""
+			if len(tokens) > 2 {
+				value = tokens[2]
+			}
+			current.options[xorgOptionName(tokens[1])] = value
+		default:
+			value := ""
+			if len(tokens) > 1 {
+				value = tokens[1]
+			}
+			current.entries[keyword] = value
+		}
+	}
+	if current != nil {
+		return nil, parseError("xorg.conf: Section %q is not closed", current.name)
+	}
+	return sections, nil
+}
+
+// tokenizeXorgLine splits a line of an xorg.conf file into keywords and
+// quoted strings, dropping comments.
+func tokenizeXorgLine(line string) ([]string, error) {
+	var tokens []string
+	for {
+		line = strings.TrimLeft(line, " \t\r")
+		if line == "" || line[0] == '#' {
+			return tokens, nil
+		}
+		if line[0] == '"' {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, line[1:end+1])
+			line = line[end+2:]
+			continue
+		}
+		end := strings.IndexAny(line, " \t\r\"#")
+		if end < 0 {
+			end = len(line)
+		}
+		tokens = append(tokens, line[:end])
+		line = line[end:]
+	}
+}
+
+// xorgOptionName normalizes an option name the way the X server compares
+// them: case-insensitively, ignoring underscores and spaces.
+func xorgOptionName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", " ", "").Replace(name))
+}
+
+// xorgBool reports whether an xorg.conf boolean value is true.
+func xorgBool(v string) bool {
+	switch strings.ToLower(v) {
+	case "", "1", "on", "true", "yes":
+		return true
+	}
+	return false
+}
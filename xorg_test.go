@@ -0,0 +1,96 @@
+package keyloc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testXorgKeyboardConf is a 00-keyboard.conf as written by systemd-localed.
+const testXorgKeyboardConf = `# Written by systemd-localed(8), read by systemd-localed and Xorg. It's
+# probably wise not to edit this file manually. Use localectl(1) to
+# instruct systemd-localed to update it.
+Section "InputClass"
+        Identifier "system-keyboard"
+        MatchIsKeyboard "on"
+        Option "XkbLayout" "us,ru"
+        Option "XkbModel" "pc105"
+        Option "XkbVariant" ",winkeys"
+        Option "XkbOptions" "grp:alt_shift_toggle"
+EndSection
+`
+
+func TestParseXorgConf(t *testing.T) {
+	sections, err := parseXorgConf([]byte(testXorgKeyboardConf + `
+Section "Screen"   # With a comment
+	Identifier "screen0"
+	SubSection "Display"
+		Depth 24
+		Option "XkbLayout" "ignored"
+	EndSubSection
+EndSection
+`))
+	if err != nil {
+		t.Fatalf("parseXorgConf() returned an error: %v", err)
+	}
+	if len(sections) != 2 {
+		t.Fatalf("parseXorgConf() returned %d sections, want 2", len(sections))
+	}
+	s := sections[0]
+	if s.name != "InputClass" || s.entries["identifier"] != "system-keyboard" || s.entries["matchiskeyboard"] != "on" {
+		t.Errorf("parseXorgConf() section = %+v", s)
+	}
+	if s.options["xkblayout"] != "us,ru" || s.options["xkbvariant"] != ",winkeys" || s.options["xkboptions"] != "grp:alt_shift_toggle" {
+		t.Errorf("parseXorgConf() options = %v", s.options)
+	}
+	if _, ok := sections[1].options["xkblayout"]; ok {
+		t.Errorf("parseXorgConf() kept an option of a subsection")
+	}
+
+	for _, input := range []string{
+		"Section \"InputClass\"\n",
+		"Option \"XkbLayout\" \"us\"\n",
+		"Section \"InputClass\"\n\tOption \"XkbLayout\" \"us\nEndSection\n",
+	} {
+		if _, err := parseXorgConf([]byte(input)); !errors.Is(err, ErrParse) {
+			t.Errorf("parseXorgConf(%q) error = %v, want %v", input, err, ErrParse)
+		}
+	}
+}
+
+func TestReadXorgKeyboard(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"etc/X11/xorg.conf.d/00-keyboard.conf": testXorgKeyboardConf,
+		// Later sections override earlier ones, unless they do not match
+		// keyboards or only match some devices
+		"etc/X11/xorg.conf.d/10-variant.conf":        "Section \"InputClass\"\n\tIdentifier \"dvorak\"\n\tOption \"Xkb_Variant\" \"dvorak,\"\nEndSection\n",
+		"etc/X11/xorg.conf.d/20-touchpad.conf":       "Section \"InputClass\"\n\tIdentifier \"touchpad\"\n\tMatchIsKeyboard \"off\"\n\tOption \"XkbLayout\" \"de\"\nEndSection\n",
+		"etc/X11/xorg.conf.d/30-keyboard.conf":       "Section \"InputClass\"\n\tIdentifier \"ergodox\"\n\tMatchIsKeyboard \"on\"\n\tMatchProduct \"ErgoDox\"\n\tOption \"XkbVariant\" \"colemak,\"\nEndSection\n",
+		"etc/X11/xorg.conf.d/31-device.conf":         "Section \"InputDevice\"\n\tIdentifier \"keyboard0\"\n\tDriver \"kbd\"\n\tOption \"XkbModel\" \"pc104\"\nEndSection\n",
+		"usr/share/X11/xorg.conf.d/40-libinput.conf": "Section \"InputClass\"\n\tIdentifier \"libinput keyboard catchall\"\n\tMatchIsKeyboard \"on\"\n\tDriver \"libinput\"\nEndSection\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	kb, ok, err := readXorgKeyboard(xorgConfigFiles(root))
+	if err != nil || !ok {
+		t.Fatalf("readXorgKeyboard() = %v, %v", ok, err)
+	}
+	expected := xorgKeyboard{layout: "us,ru", variant: "dvorak,", model: "pc105", options: "grp:alt_shift_toggle"}
+	if kb != expected {
+		t.Errorf("readXorgKeyboard() = %+v, want %+v", kb, expected)
+	}
+
+	if _, ok, err := readXorgKeyboard(xorgConfigFiles(t.TempDir())); ok || err != nil {
+		t.Errorf("readXorgKeyboard() without configuration = %v, %v, want nothing", ok, err)
+	}
+}